@@ -0,0 +1,208 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package alert evaluates alerting rules over a rolling window of samples
+// and reports the changes of state of each rule as events.
+//
+// A Monitor keeps the last samples in a circular buffer and evaluates its
+// rules over that window on every sample. Rules answer in O(1) per sample:
+// NOfM keeps a running count of the breaches in the window, updated with
+// the sample coming in and the one going out of its last m, Hysteresis
+// remembers which side of its band it last crossed. Events are
+// delivered to a callback, a channel or both, and a flapping rule can be
+// held in the Flapping state until it settles.
+package alert
+
+import "github.com/gus-maurizio/structures/circularbuffer"
+
+// State is the state of a rule.
+type State int
+
+const (
+	// OK means the rule is not breached.
+	OK State = iota
+	// Firing means the rule is breached.
+	Firing
+	// Flapping means the rule changed state too often to be trusted.
+	Flapping
+)
+
+func (s State) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Firing:
+		return "firing"
+	case Flapping:
+		return "flapping"
+	}
+	return "unknown"
+}
+
+// Event reports that a rule changed state on the Seq-th sample pushed.
+type Event[T any] struct {
+	Rule  string
+	From  State
+	To    State
+	Value T
+	Seq   uint64
+}
+
+// Rising reports whether the event is the rule starting to fire.
+func (e Event[T]) Rising() bool { return e.To == Firing }
+
+// Falling reports whether the event is the rule going back to OK.
+func (e Event[T]) Falling() bool { return e.To == OK }
+
+// Rule decides the state of an alert from the stream of samples.
+type Rule[T any] interface {
+	// Name identifies the rule in events.
+	Name() string
+	// Observe takes the next sample v and the monitor's window, which
+	// holds the samples before v, oldest first, and returns the state
+	// they leave the rule in. v is added to the window after every rule
+	// has observed it.
+	Observe(window *circularbuffer.CircularBuffer[T], v T) State
+}
+
+// rule is the state a Monitor keeps for each of its rules.
+type rule[T any] struct {
+	Rule[T]
+	raw   State // last state returned by Observe
+	state State // last state reported
+	// changes records whether raw changed on each of the last samples.
+	changes *circularbuffer.CircularBuffer[bool]
+	nchange int
+}
+
+// Monitor feeds samples to a set of rules and emits their state changes.
+type Monitor[T any] struct {
+	window     *circularbuffer.CircularBuffer[T]
+	rules      []*rule[T]
+	seq        uint64
+	callback   func(Event[T])
+	events     chan<- Event[T]
+	flapMax    int
+	flapWindow int
+}
+
+// Option configures a Monitor when passed to New.
+type Option[T any] func(*Monitor[T])
+
+// WithCallback makes the monitor call f with every event, in order, from
+// the goroutine calling Push.
+func WithCallback[T any](f func(Event[T])) Option[T] {
+	return func(m *Monitor[T]) { m.callback = f }
+}
+
+// WithChannel makes the monitor send every event on ch. Push blocks
+// until each event is received, so ch should be buffered or drained by
+// another goroutine.
+func WithChannel[T any](ch chan<- Event[T]) Option[T] {
+	return func(m *Monitor[T]) { m.events = ch }
+}
+
+// WithFlapSuppression puts a rule in the Flapping state when it changes
+// state more than maxChanges times within the last window samples. No
+// events are emitted for the rule while it flaps; once it has gone window
+// samples without changing, its current state is reported again.
+func WithFlapSuppression[T any](maxChanges, window int) Option[T] {
+	return func(m *Monitor[T]) {
+		m.flapMax = maxChanges
+		m.flapWindow = window
+	}
+}
+
+// New returns a monitor keeping the last size samples, initially all set
+// to initval, and without rules.
+func New[T any](size int, initval T, opts ...Option[T]) *Monitor[T] {
+	m := &Monitor[T]{window: circularbuffer.New(size, initval)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// AddRule adds r to the rules evaluated on every Push. The rule starts OK.
+func (m *Monitor[T]) AddRule(r Rule[T]) {
+	ru := &rule[T]{Rule: r}
+	if m.flapWindow > 0 {
+		ru.changes = circularbuffer.New(m.flapWindow, false)
+	}
+	m.rules = append(m.rules, ru)
+}
+
+// Window returns the circular buffer of the last samples pushed.
+func (m *Monitor[T]) Window() *circularbuffer.CircularBuffer[T] {
+	return m.window
+}
+
+// State returns the reported state of the named rule, and false if the
+// monitor has no such rule.
+func (m *Monitor[T]) State(name string) (State, bool) {
+	for _, r := range m.rules {
+		if r.Name() == name {
+			return r.state, true
+		}
+	}
+	return OK, false
+}
+
+// Push evaluates every rule on v and the window, emits the resulting
+// state changes and adds v to the window.
+func (m *Monitor[T]) Push(v T) {
+	m.seq++
+	for _, r := range m.rules {
+		raw := r.Observe(m.window, v)
+		changed := raw != r.raw
+		r.raw = raw
+
+		next := raw
+		if r.changes != nil {
+			if r.changes.Push(changed) {
+				r.nchange--
+			}
+			if changed {
+				r.nchange++
+			}
+			switch {
+			case r.nchange > m.flapMax:
+				next = Flapping
+			case r.state == Flapping && r.nchange > 0:
+				// Not settled yet.
+				next = Flapping
+			}
+		}
+		if next != r.state {
+			m.emit(Event[T]{Rule: r.Name(), From: r.state, To: next, Value: v, Seq: m.seq})
+			r.state = next
+		}
+	}
+	m.window.Push(v)
+}
+
+func (m *Monitor[T]) emit(e Event[T]) {
+	if m.callback != nil {
+		m.callback(e)
+	}
+	if m.events != nil {
+		m.events <- e
+	}
+}
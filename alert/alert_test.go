@@ -0,0 +1,188 @@
+package alert
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gus-maurizio/structures/circularbuffer"
+)
+
+type measure struct {
+	msgNum  int
+	isAlert bool
+}
+
+// collect returns a monitor with rule r recording its events.
+func collect[T any](r Rule[T], opts ...Option[T]) (*Monitor[T], *[]Event[T]) {
+	var events []Event[T]
+	var zero T
+	opts = append(opts, WithCallback(func(e Event[T]) { events = append(events, e) }))
+	m := New(8, zero, opts...)
+	m.AddRule(r)
+	return m, &events
+}
+
+func transitions[T any](events []Event[T]) []State {
+	var s []State
+	for _, e := range events {
+		s = append(s, e.To)
+	}
+	return s
+}
+
+func TestThresholdEdges(t *testing.T) {
+	m, events := collect(Threshold("alert", func(v measure) bool { return v.isAlert }))
+	for i, a := range []bool{false, true, true, false, true} {
+		m.Push(measure{msgNum: i + 1, isAlert: a})
+	}
+	if len(*events) != 3 {
+		t.Fatal("events", *events)
+	}
+	if e := (*events)[0]; !e.Rising() || e.Value.msgNum != 2 || e.Seq != 2 {
+		t.Error("first event", e)
+	}
+	if e := (*events)[1]; !e.Falling() || e.Value.msgNum != 4 {
+		t.Error("second event", e)
+	}
+	if s, _ := m.State("alert"); s != Firing {
+		t.Error("State() =", s)
+	}
+	if got := m.Window().Get(-1); got.msgNum != 5 {
+		t.Error("window newest", got)
+	}
+}
+
+func TestNOfM(t *testing.T) {
+	m, events := collect(NOfM("cpu", 3, 5, func(v float64) bool { return v > 90 }))
+	samples := []float64{95, 50, 95, 50, 95, 95, 50, 50, 50, 50}
+	var firing []bool
+	for _, v := range samples {
+		m.Push(v)
+		s, _ := m.State("cpu")
+		firing = append(firing, s == Firing)
+	}
+	// Fires on the third breach within 5, clears when the window of 5
+	// holds only two.
+	want := []bool{false, false, false, false, true, true, true, false, false, false}
+	if !slices.Equal(firing, want) {
+		t.Error("firing", firing, "expected", want)
+	}
+	if len(*events) != 2 {
+		t.Error("events", *events)
+	}
+}
+
+// TestNOfMWindow checks NOfM against a count over the monitor's window,
+// whose initial values breach until pushed out.
+func TestNOfMWindow(t *testing.T) {
+	breach := func(v int) bool { return v%3 == 0 }
+	for _, size := range []int{1, 4, 8} {
+		for _, m := range []int{1, 2, 3, 8, 12} {
+			mon := New(size, 3)
+			mon.AddRule(NOfM("r", 2, m, breach))
+			history := slices.Repeat([]int{3}, size)
+			for i := 0; i < 40; i++ {
+				v := i * 7 % 11
+				mon.Push(v)
+				history = append(history, v)
+				count := 0
+				for _, x := range history[len(history)-min(m, size):] {
+					if breach(x) {
+						count++
+					}
+				}
+				if s, _ := mon.State("r"); (s == Firing) != (count >= 2) {
+					t.Fatalf("size %d, m %d, sample %d: state %v with %d breaches", size, m, i, s, count)
+				}
+			}
+		}
+	}
+}
+
+// meanAbove fires while the mean of the window and the new sample is
+// above a limit.
+type meanAbove float64
+
+func (meanAbove) Name() string { return "mean" }
+
+func (r meanAbove) Observe(window *circularbuffer.CircularBuffer[float64], v float64) State {
+	sum := v
+	for x := range window.Values() {
+		sum += x
+	}
+	if sum/float64(window.Len+1) > float64(r) {
+		return Firing
+	}
+	return OK
+}
+
+func TestWindowRule(t *testing.T) {
+	m := New(3, 0.0)
+	m.AddRule(meanAbove(10))
+	var states []State
+	for _, v := range []float64{20, 20, 20, 0, 0, 0} {
+		m.Push(v)
+		s, _ := m.State("mean")
+		states = append(states, s)
+	}
+	if want := []State{OK, OK, Firing, Firing, OK, OK}; !slices.Equal(states, want) {
+		t.Error("states", states, "expected", want)
+	}
+}
+
+func TestHysteresis(t *testing.T) {
+	id := func(v float64) float64 { return v }
+	m, events := collect(Hysteresis("temp", id, 80, 70))
+	for _, v := range []float64{75, 81, 75, 79, 70, 75, 80} {
+		m.Push(v)
+	}
+	if got := transitions(*events); !slices.Equal(got, []State{Firing, OK, Firing}) {
+		t.Error("transitions", got)
+	}
+
+	m, events = collect(Hysteresis("disk", id, 10, 20))
+	for _, v := range []float64{50, 10, 15, 19, 20, 15} {
+		m.Push(v)
+	}
+	if got := transitions(*events); !slices.Equal(got, []State{Firing, OK}) {
+		t.Error("low side transitions", got)
+	}
+}
+
+func TestFlapSuppression(t *testing.T) {
+	breach := func(v int) bool { return v > 0 }
+	m, events := collect(Threshold("flap", breach), WithFlapSuppression[int](2, 4))
+	// Toggling every sample: rising, falling, then flapping on the third
+	// change within 4 samples.
+	for _, v := range []int{1, 0, 1, 0, 1, 0} {
+		m.Push(v)
+	}
+	if got := transitions(*events); !slices.Equal(got, []State{Firing, OK, Flapping}) {
+		t.Fatal("transitions", got)
+	}
+	// One more change, then steady for 4 samples, reports the real state
+	// once.
+	for i := 0; i < 5; i++ {
+		m.Push(1)
+	}
+	if got := transitions(*events); !slices.Equal(got, []State{Firing, OK, Flapping, Firing}) {
+		t.Error("transitions after settling", got)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	ch := make(chan Event[int], 4)
+	m := New(4, 0, WithChannel(ch))
+	m.AddRule(Threshold("a", func(v int) bool { return v > 5 }))
+	m.AddRule(Threshold("b", func(v int) bool { return v > 8 }))
+	m.Push(9)
+	m.Push(1)
+	close(ch)
+	var names []string
+	for e := range ch {
+		names = append(names, e.Rule+":"+e.To.String())
+	}
+	if !slices.Equal(names, []string{"a:firing", "b:firing", "a:ok", "b:ok"}) {
+		t.Error("events", names)
+	}
+}
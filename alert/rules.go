@@ -0,0 +1,133 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package alert
+
+import "github.com/gus-maurizio/structures/circularbuffer"
+
+// threshold fires while the latest sample breaches.
+type threshold[T any] struct {
+	name   string
+	breach func(T) bool
+}
+
+// Threshold returns a rule that fires while breach is true for the latest
+// sample. Its events are the rising and falling edges of breach.
+func Threshold[T any](name string, breach func(T) bool) Rule[T] {
+	return &threshold[T]{name, breach}
+}
+
+func (r *threshold[T]) Name() string { return r.name }
+
+func (r *threshold[T]) Observe(_ *circularbuffer.CircularBuffer[T], v T) State {
+	if r.breach(v) {
+		return Firing
+	}
+	return OK
+}
+
+// nOfM fires while at least n of the last m samples breach.
+type nOfM[T any] struct {
+	name   string
+	n, m   int
+	breach func(T) bool
+	primed bool
+	count  int // breaches among the last m-1 samples of the window
+}
+
+// NOfM returns a rule that fires while breach is true for at least n of
+// the last m samples, such as "3 of the last 5 above 90". The samples
+// are those of the monitor's window, so an m larger than the window is
+// taken as its size, and the initial values of the window count until
+// they are pushed out. breach must always give the same answer for the
+// same sample.
+func NOfM[T any](name string, n, m int, breach func(T) bool) Rule[T] {
+	return &nOfM[T]{name: name, n: n, m: max(m, 1), breach: breach}
+}
+
+func (r *nOfM[T]) Name() string { return r.name }
+
+func (r *nOfM[T]) Observe(window *circularbuffer.CircularBuffer[T], v T) State {
+	if !r.primed {
+		r.m = min(r.m, window.Len)
+		for i := 1; i < r.m; i++ {
+			if r.breach(window.Get(-i)) {
+				r.count++
+			}
+		}
+		r.primed = true
+	}
+	// The last m samples are v and the m-1 newest of the window. Once v
+	// is in the window, the oldest of those m is no longer among the m-1
+	// newest, so it leaves the count carried to the next sample.
+	n := r.count
+	if r.breach(v) {
+		n++
+	}
+	r.count = n
+	if r.m == 1 {
+		r.count = 0
+	} else if r.breach(window.Get(-(r.m - 1))) {
+		r.count--
+	}
+	if n >= r.n {
+		return Firing
+	}
+	return OK
+}
+
+// hysteresis fires above a high mark and clears below a low one.
+type hysteresis[T any] struct {
+	name      string
+	value     func(T) float64
+	high, low float64
+	state     State
+}
+
+// Hysteresis returns a rule that starts firing when the value of a sample
+// reaches high and stops only when a value falls to low or below, so that
+// values moving within the band do not toggle it. For a rule on low
+// values, such as free disk space, pass high < low: it fires at or below
+// high and clears at or above low.
+func Hysteresis[T any](name string, value func(T) float64, high, low float64) Rule[T] {
+	return &hysteresis[T]{name: name, value: value, high: high, low: low}
+}
+
+func (r *hysteresis[T]) Name() string { return r.name }
+
+func (r *hysteresis[T]) Observe(_ *circularbuffer.CircularBuffer[T], v T) State {
+	x := r.value(v)
+	if r.high >= r.low {
+		switch {
+		case x >= r.high:
+			r.state = Firing
+		case x <= r.low:
+			r.state = OK
+		}
+	} else {
+		switch {
+		case x <= r.high:
+			r.state = Firing
+		case x >= r.low:
+			r.state = OK
+		}
+	}
+	return r.state
+}
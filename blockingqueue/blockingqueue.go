@@ -0,0 +1,160 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package blockingqueue provides a bounded FIFO queue for passing values
+// between goroutines. It is built on the deque ring buffer; Put waits while
+// the queue is full and Take waits while it is empty, and both give up when
+// their context is cancelled or its deadline passes.
+package blockingqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gus-maurizio/structures/deque/v2"
+	"github.com/gus-maurizio/structures/internal/broadcast"
+)
+
+// ErrClosed is returned by Put once the queue is closed, and by Take once
+// the queue is closed and empty.
+var ErrClosed = errors.New("blockingqueue: queue closed")
+
+// Queue is a bounded FIFO queue safe for use by several goroutines.
+type Queue[T any] struct {
+	mu       sync.Mutex
+	q        deque.Deque[T]
+	capacity int
+	closed   bool
+	notFull  broadcast.Signal
+	notEmpty broadcast.Signal
+}
+
+// New returns an empty queue holding at most capacity elements.
+// A capacity below 1 is taken as 1.
+func New[T any](capacity int) *Queue[T] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Queue[T]{capacity: capacity}
+}
+
+// Cap returns the maximum number of elements the queue holds.
+func (b *Queue[T]) Cap() int {
+	return b.capacity
+}
+
+// Len returns the number of elements in the queue.
+func (b *Queue[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.q.Len()
+}
+
+// Put appends v to the queue, waiting while the queue is full. It returns
+// ErrClosed if the queue is closed before v is added, or ctx.Err() if ctx
+// is done first.
+func (b *Queue[T]) Put(ctx context.Context, v T) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		if b.closed {
+			return ErrClosed
+		}
+		if b.q.Len() < b.capacity {
+			break
+		}
+		if err := b.wait(ctx, b.notFull.Wait()); err != nil {
+			return err
+		}
+	}
+	b.q.PushLast(v)
+	b.notEmpty.Broadcast()
+	return nil
+}
+
+// TryPut appends v to the queue if there is room, without waiting.
+func (b *Queue[T]) TryPut(v T) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || b.q.Len() >= b.capacity {
+		return false
+	}
+	b.q.PushLast(v)
+	b.notEmpty.Broadcast()
+	return true
+}
+
+// Take removes and returns the first element of the queue, waiting while
+// the queue is empty. Elements still queued when the queue is closed are
+// returned; after that Take returns ErrClosed. If ctx is done first it
+// returns ctx.Err().
+func (b *Queue[T]) Take(ctx context.Context) (T, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.q.Len() == 0 {
+		if b.closed {
+			var zero T
+			return zero, ErrClosed
+		}
+		if err := b.wait(ctx, b.notEmpty.Wait()); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+	v, _ := b.q.PopFirst()
+	b.notFull.Broadcast()
+	return v, nil
+}
+
+// TryTake removes and returns the first element of the queue if there is
+// one, without waiting.
+func (b *Queue[T]) TryTake() (T, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.q.PopFirst()
+	if ok {
+		b.notFull.Broadcast()
+	}
+	return v, ok
+}
+
+// Close stops the queue from accepting elements and wakes every waiting
+// Put and Take. Closing a closed queue has no effect.
+func (b *Queue[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.notFull.Broadcast()
+	b.notEmpty.Broadcast()
+}
+
+// wait releases the lock until ch is closed or ctx is done, and takes it
+// again before returning.
+func (b *Queue[T]) wait(ctx context.Context, ch <-chan struct{}) error {
+	b.mu.Unlock()
+	defer b.mu.Lock()
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
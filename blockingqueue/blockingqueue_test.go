@@ -0,0 +1,148 @@
+package blockingqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFIFO(t *testing.T) {
+	q := New[int](3)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := q.Put(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.TryPut(3) {
+		t.Error("TryPut succeeded on full queue")
+	}
+	for i := 0; i < 3; i++ {
+		if v, err := q.Take(ctx); err != nil || v != i {
+			t.Error("Take() =", v, err, "expected", i)
+		}
+	}
+	if _, ok := q.TryTake(); ok {
+		t.Error("TryTake succeeded on empty queue")
+	}
+}
+
+func TestPutBlocksWhenFull(t *testing.T) {
+	q := New[int](1)
+	q.Put(context.Background(), 1)
+
+	done := make(chan error)
+	go func() { done <- q.Put(context.Background(), 2) }()
+	select {
+	case <-done:
+		t.Fatal("Put did not wait on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if v, _ := q.Take(context.Background()); v != 1 {
+		t.Error("Take() =", v, "expected 1")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := q.Take(context.Background()); v != 2 {
+		t.Error("Take() =", v, "expected 2")
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	q := New[int](1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Take(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Take() on empty queue returned", err)
+	}
+
+	q.Put(context.Background(), 1)
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := q.Put(ctx, 2); !errors.Is(err, context.Canceled) {
+		t.Error("Put() on full queue returned", err)
+	}
+	if q.Len() != 1 {
+		t.Error("cancelled Put changed length to", q.Len())
+	}
+}
+
+func TestCloseWakesWaiters(t *testing.T) {
+	q := New[int](1)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := q.Take(context.Background()); err != ErrClosed {
+				t.Error("Take() after Close returned", err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	wg.Wait()
+
+	if err := q.Put(context.Background(), 1); err != ErrClosed {
+		t.Error("Put() after Close returned", err)
+	}
+}
+
+func TestCloseDrains(t *testing.T) {
+	q := New[string](2)
+	q.Put(context.Background(), "a")
+	q.Close()
+	if v, err := q.Take(context.Background()); err != nil || v != "a" {
+		t.Error("Take() after Close =", v, err, "expected a")
+	}
+	if _, err := q.Take(context.Background()); err != ErrClosed {
+		t.Error("Take() on drained queue returned", err)
+	}
+}
+
+func TestProducersConsumers(t *testing.T) {
+	q := New[int](8)
+	ctx := context.Background()
+	const producers, items = 4, 1000
+	var pwg, cwg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		pwg.Add(1)
+		go func(p int) {
+			defer pwg.Done()
+			for i := 0; i < items; i++ {
+				q.Put(ctx, p*items+i)
+			}
+		}(p)
+	}
+	sums := make([]int, producers)
+	for c := 0; c < producers; c++ {
+		cwg.Add(1)
+		go func(c int) {
+			defer cwg.Done()
+			for {
+				v, err := q.Take(ctx)
+				if err != nil {
+					return
+				}
+				sums[c] += v
+			}
+		}(c)
+	}
+	pwg.Wait()
+	q.Close()
+	cwg.Wait()
+
+	total, n := 0, producers*items
+	for _, s := range sums {
+		total += s
+	}
+	if total != n*(n-1)/2 {
+		t.Error("consumers took", total, "expected", n*(n-1)/2)
+	}
+}
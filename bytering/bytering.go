@@ -0,0 +1,280 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package bytering provides a bounded byte pipe on a ring buffer whose
+// size is a power of two, so positions wrap with a mask as in deque.
+// A Buffer implements io.Reader, io.Writer, io.ByteReader, io.WriterTo,
+// io.ReaderFrom and io.Closer, and is safe for use by several goroutines.
+//
+// In Overwrite mode a write into a full buffer drops the oldest bytes,
+// which keeps the tail of a stream such as the output of a subprocess.
+// In Block mode a write waits for readers to make room.
+package bytering
+
+import (
+	"errors"
+	"io"
+	"math/bits"
+	"sync"
+
+	"github.com/gus-maurizio/structures/internal/broadcast"
+)
+
+// Mode says what a write does when the buffer is full.
+type Mode int
+
+const (
+	// Overwrite drops the oldest bytes to make room; writes never wait.
+	Overwrite Mode = iota
+	// Block waits until readers make room.
+	Block
+)
+
+// ErrClosed is returned by writes to a closed Buffer.
+var ErrClosed = errors.New("bytering: buffer closed")
+
+// copySize is the size of the chunks moved by WriteTo and ReadFrom.
+const copySize = 32 << 10
+
+// Buffer is a bounded FIFO of bytes.
+type Buffer struct {
+	mu       sync.Mutex
+	buf      []byte
+	head     int
+	count    int
+	mode     Mode
+	dropped  int64
+	closed   bool
+	notFull  broadcast.Signal
+	notEmpty broadcast.Signal
+}
+
+// New returns an empty buffer holding at least size bytes; the size is
+// rounded up to a power of two, and a size below 1 is taken as 1.
+func New(size int, mode Mode) *Buffer {
+	if size < 1 {
+		size = 1
+	}
+	return &Buffer{buf: make([]byte, 1<<bits.Len(uint(size-1))), mode: mode}
+}
+
+// Cap returns the number of bytes the buffer holds.
+func (b *Buffer) Cap() int { return len(b.buf) }
+
+// Len returns the number of unread bytes in the buffer.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// Dropped returns the number of bytes discarded by writes in Overwrite
+// mode before anyone read them.
+func (b *Buffer) Dropped() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// Bytes returns a copy of the unread bytes, without consuming them.
+func (b *Buffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := make([]byte, b.count)
+	b.peek(p)
+	return p
+}
+
+// Reset discards the unread bytes.
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.head, b.count = 0, 0
+	b.notFull.Broadcast()
+}
+
+// Close stops the buffer from accepting writes and wakes every waiting
+// reader and writer. Reads return the remaining bytes and then io.EOF.
+// Closing a closed buffer has no effect.
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.notFull.Broadcast()
+	b.notEmpty.Broadcast()
+	return nil
+}
+
+// Write appends p to the buffer. In Overwrite mode it always writes all
+// of p, dropping the oldest bytes as needed; in Block mode it waits for
+// room as often as needed. It returns ErrClosed if the buffer is closed
+// before all of p is written.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, ErrClosed
+	}
+	if b.mode == Overwrite {
+		b.overwrite(p)
+		return len(p), nil
+	}
+	n := 0
+	for n < len(p) {
+		if b.closed {
+			return n, ErrClosed
+		}
+		free := len(b.buf) - b.count
+		if free == 0 {
+			b.wait(&b.notFull)
+			continue
+		}
+		k := min(free, len(p)-n)
+		b.put(p[n : n+k])
+		n += k
+		b.notEmpty.Broadcast()
+	}
+	return n, nil
+}
+
+// WriteByte appends c to the buffer, as Write does.
+func (b *Buffer) WriteByte(c byte) error {
+	_, err := b.Write([]byte{c})
+	return err
+}
+
+// Read reads up to len(p) bytes, waiting while the buffer is empty. Once
+// the buffer is closed and empty it returns io.EOF.
+func (b *Buffer) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.count == 0 {
+		if b.closed {
+			return 0, io.EOF
+		}
+		b.wait(&b.notEmpty)
+	}
+	n := b.peek(p)
+	b.discard(n)
+	b.notFull.Broadcast()
+	return n, nil
+}
+
+// ReadByte reads one byte, waiting while the buffer is empty. Once the
+// buffer is closed and empty it returns io.EOF.
+func (b *Buffer) ReadByte() (byte, error) {
+	var p [1]byte
+	if _, err := b.Read(p[:]); err != nil {
+		return 0, err
+	}
+	return p[0], nil
+}
+
+// WriteTo reads from the buffer into w until the buffer is closed and
+// empty or w fails.
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	p := make([]byte, min(copySize, len(b.buf)))
+	var total int64
+	for {
+		n, err := b.Read(p)
+		if err == io.EOF {
+			return total, nil
+		}
+		m, err := w.Write(p[:n])
+		total += int64(m)
+		if err != nil {
+			return total, err
+		}
+		if m < n {
+			return total, io.ErrShortWrite
+		}
+	}
+}
+
+// ReadFrom writes everything read from r into the buffer until r returns
+// io.EOF or an error, or the buffer is closed.
+func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
+	p := make([]byte, min(copySize, len(b.buf)))
+	var total int64
+	for {
+		n, err := r.Read(p)
+		if n > 0 {
+			m, werr := b.Write(p[:n])
+			total += int64(m)
+			if werr != nil {
+				return total, werr
+			}
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// overwrite writes p, dropping the oldest bytes to make room.
+func (b *Buffer) overwrite(p []byte) {
+	if extra := len(p) - len(b.buf); extra > 0 {
+		b.dropped += int64(extra)
+		p = p[extra:]
+	}
+	if over := b.count + len(p) - len(b.buf); over > 0 {
+		b.discard(over)
+		b.dropped += int64(over)
+	}
+	b.put(p)
+	b.notEmpty.Broadcast()
+}
+
+// put appends p, which must fit in the free space.
+func (b *Buffer) put(p []byte) {
+	tail := (b.head + b.count) & (len(b.buf) - 1)
+	n := copy(b.buf[tail:], p)
+	copy(b.buf, p[n:])
+	b.count += len(p)
+}
+
+// peek copies up to len(p) unread bytes into p, oldest first.
+func (b *Buffer) peek(p []byte) int {
+	n := min(len(p), b.count)
+	m := copy(p[:n], b.buf[b.head:])
+	copy(p[m:n], b.buf)
+	return n
+}
+
+// discard drops the n oldest bytes.
+func (b *Buffer) discard(n int) {
+	b.head = (b.head + n) & (len(b.buf) - 1)
+	b.count -= n
+}
+
+// wait releases the lock until s is broadcast, and takes it again before
+// returning.
+func (b *Buffer) wait(s *broadcast.Signal) {
+	ch := s.Wait()
+	b.mu.Unlock()
+	<-ch
+	b.mu.Lock()
+}
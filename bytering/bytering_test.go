@@ -0,0 +1,158 @@
+package bytering
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+var (
+	_ io.ReadWriteCloser = (*Buffer)(nil)
+	_ io.ByteReader      = (*Buffer)(nil)
+	_ io.ByteWriter      = (*Buffer)(nil)
+	_ io.WriterTo        = (*Buffer)(nil)
+	_ io.ReaderFrom      = (*Buffer)(nil)
+)
+
+func TestNewRoundsUp(t *testing.T) {
+	for size, want := range map[int]int{-1: 1, 0: 1, 1: 1, 5: 8, 8: 8, 1000: 1024} {
+		if got := New(size, Block).Cap(); got != want {
+			t.Errorf("New(%d).Cap() = %d, expected %d", size, got, want)
+		}
+	}
+}
+
+func TestReadWriteWrap(t *testing.T) {
+	b := New(8, Block)
+	p := make([]byte, 8)
+	for i := 0; i < 5; i++ {
+		if n, err := b.Write([]byte("abcde")); n != 5 || err != nil {
+			t.Fatal("Write returned", n, err)
+		}
+		if b.Len() != 5 || string(b.Bytes()) != "abcde" {
+			t.Fatalf("round %d: Len %d, Bytes %q", i, b.Len(), b.Bytes())
+		}
+		if n, err := b.Read(p[:3]); n != 3 || err != nil || string(p[:3]) != "abc" {
+			t.Fatalf("round %d: Read gave %q, %v", i, p[:n], err)
+		}
+		if n, _ := b.Read(p); string(p[:n]) != "de" {
+			t.Fatalf("round %d: Read gave %q", i, p[:n])
+		}
+	}
+	b.WriteByte('x')
+	if c, err := b.ReadByte(); c != 'x' || err != nil {
+		t.Error("ReadByte returned", c, err)
+	}
+}
+
+func TestOverwrite(t *testing.T) {
+	b := New(8, Overwrite)
+	b.Write([]byte("0123456"))
+	if n, err := b.Write([]byte("789")); n != 3 || err != nil {
+		t.Fatal("Write returned", n, err)
+	}
+	if string(b.Bytes()) != "23456789" || b.Dropped() != 2 {
+		t.Errorf("Bytes %q, Dropped %d", b.Bytes(), b.Dropped())
+	}
+	b.Write([]byte("abcdefghijkl"))
+	if string(b.Bytes()) != "efghijkl" || b.Dropped() != 14 {
+		t.Errorf("Bytes %q, Dropped %d", b.Bytes(), b.Dropped())
+	}
+	b.Reset()
+	if b.Len() != 0 {
+		t.Error("Len after Reset =", b.Len())
+	}
+}
+
+func TestBlockWaitsForRoom(t *testing.T) {
+	b := New(4, Block)
+	done := make(chan error)
+	go func() {
+		_, err := b.Write([]byte("0123456789"))
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("Write of 10 bytes into 4 returned without a reader")
+	case <-time.After(10 * time.Millisecond):
+	}
+	got, err := io.ReadAll(io.LimitReader(b, 10))
+	if err != nil || string(got) != "0123456789" {
+		t.Fatalf("read %q, %v", got, err)
+	}
+	if err := <-done; err != nil {
+		t.Error("Write returned", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := New(4, Block)
+	b.Write([]byte("abcd"))
+	written := make(chan error)
+	go func() {
+		_, err := b.Write([]byte("e"))
+		written <- err
+	}()
+	read := make(chan error)
+	empty := New(4, Block)
+	go func() {
+		_, err := empty.ReadByte()
+		read <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	b.Close()
+	empty.Close()
+	if err := <-written; err != ErrClosed {
+		t.Error("blocked Write returned", err)
+	}
+	if err := <-read; err != io.EOF {
+		t.Error("blocked ReadByte returned", err)
+	}
+	if got, err := io.ReadAll(b); string(got) != "abcd" || err != nil {
+		t.Errorf("ReadAll after Close gave %q, %v", got, err)
+	}
+	if _, err := b.Write([]byte("x")); err != ErrClosed {
+		t.Error("Write after Close returned", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	b := New(16, Block)
+	src := strings.Repeat("the quick brown fox ", 1000)
+	go func() {
+		n, err := b.ReadFrom(iotest.OneByteReader(strings.NewReader(src)))
+		if n != int64(len(src)) || err != nil {
+			t.Error("ReadFrom returned", n, err)
+		}
+		b.Close()
+	}()
+	var dst bytes.Buffer
+	n, err := b.WriteTo(&dst)
+	if n != int64(len(src)) || err != nil || dst.String() != src {
+		t.Errorf("WriteTo returned %d, %v", n, err)
+	}
+}
+
+func TestWriteToError(t *testing.T) {
+	b := New(8, Overwrite)
+	b.Write([]byte("abc"))
+	b.Close()
+	boom := errors.New("boom")
+	if _, err := b.WriteTo(errWriter{boom}); err != boom {
+		t.Error("WriteTo returned", err)
+	}
+	if _, err := New(8, Block).ReadFrom(iotest.ErrReader(boom)); err != boom {
+		t.Error("ReadFrom returned", err)
+	}
+	if _, err := b.ReadFrom(strings.NewReader("x")); err != ErrClosed {
+		t.Error("ReadFrom into closed buffer returned", err)
+	}
+}
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write(p []byte) (int, error) { return 0, w.err }
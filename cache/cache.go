@@ -0,0 +1,60 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package cache provides fixed capacity LRU and LFU caches. Both keep a
+// map from keys to handles into an index-stable ring, a FIFO whose
+// elements can be moved to the back or removed in constant time and whose
+// handles survive the ring growing and compacting its buffer. The caches
+// are not safe for concurrent use by several goroutines.
+package cache
+
+// Stats counts the lookups and evictions of a cache.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// entry is a key and value held in a ring.
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+type options[K comparable, V any] struct {
+	evict func(K, V)
+}
+
+// Option configures a cache when passed to NewLRU or NewLFU.
+type Option[K comparable, V any] func(*options[K, V])
+
+// WithEvict registers f to be called with the key and value that Put
+// evicts to make room. It is not called by Remove.
+func WithEvict[K comparable, V any](f func(key K, value V)) Option[K, V] {
+	return func(o *options[K, V]) { o.evict = f }
+}
+
+func newOptions[K comparable, V any](opts []Option[K, V]) options[K, V] {
+	var o options[K, V]
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
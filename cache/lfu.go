@@ -0,0 +1,159 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package cache
+
+// LFU is a cache that evicts the least frequently used key when full,
+// and among those the least recently used. Keys are kept in one ring per
+// use count, so every operation takes constant time.
+type LFU[K comparable, V any] struct {
+	capacity int
+	items    map[K]lfuItem
+	buckets  map[int]*ring[entry[K, V]] // keys by use count
+	minCount int                        // lowest use count in buckets
+	opts     options[K, V]
+	stats    Stats
+}
+
+type lfuItem struct {
+	count int
+	h     handle
+}
+
+// NewLFU returns an empty LFU cache holding at most capacity keys. A
+// capacity below 1 is taken as 1.
+func NewLFU[K comparable, V any](capacity int, opts ...Option[K, V]) *LFU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LFU[K, V]{
+		capacity: capacity,
+		items:    make(map[K]lfuItem, capacity),
+		buckets:  make(map[int]*ring[entry[K, V]]),
+		opts:     newOptions(opts),
+	}
+}
+
+// Len returns the number of keys in the cache.
+func (c *LFU[K, V]) Len() int { return len(c.items) }
+
+// Cap returns the maximum number of keys in the cache.
+func (c *LFU[K, V]) Cap() int { return c.capacity }
+
+// Stats returns the hit, miss and eviction counts so far.
+func (c *LFU[K, V]) Stats() Stats { return c.stats }
+
+// Count returns how many times key has been put or got, or 0 if it is
+// not in the cache.
+func (c *LFU[K, V]) Count(key K) int { return c.items[key].count }
+
+// Get returns the value of key and adds one to its use count, counting a
+// hit, or counts a miss if key is not in the cache.
+func (c *LFU[K, V]) Get(key K) (V, bool) {
+	it, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	return c.touch(key, it).value, true
+}
+
+// Peek returns the value of key without using it or counting the lookup.
+func (c *LFU[K, V]) Peek(key K) (V, bool) {
+	it, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return c.buckets[it.count].value(it.h).value, true
+}
+
+// Put sets the value of key and adds one to its use count. If key is new
+// and the cache is full, the least frequently used key is evicted first
+// and Put returns true.
+func (c *LFU[K, V]) Put(key K, value V) bool {
+	if it, ok := c.items[key]; ok {
+		c.touch(key, it).value = value
+		return false
+	}
+	evicted := false
+	if len(c.items) >= c.capacity {
+		b := c.buckets[c.minCount]
+		h, _ := b.front()
+		e := c.detach(c.minCount, h)
+		delete(c.items, e.key)
+		c.stats.Evictions++
+		if c.opts.evict != nil {
+			c.opts.evict(e.key, e.value)
+		}
+		evicted = true
+	}
+	c.items[key] = lfuItem{1, c.bucket(1).pushBack(entry[K, V]{key, value})}
+	c.minCount = 1
+	return evicted
+}
+
+// Remove deletes key from the cache and reports whether it was there.
+func (c *LFU[K, V]) Remove(key K) bool {
+	it, ok := c.items[key]
+	if ok {
+		// minCount may be left naming a dropped ring, but it is only
+		// used once the cache is full again, after a Put resets it.
+		c.detach(it.count, it.h)
+		delete(c.items, key)
+	}
+	return ok
+}
+
+// touch moves key from the ring of its use count to the next one and
+// returns its entry there.
+func (c *LFU[K, V]) touch(key K, it lfuItem) *entry[K, V] {
+	e := c.detach(it.count, it.h)
+	if c.minCount == it.count && c.buckets[it.count] == nil {
+		c.minCount++
+	}
+	it.count++
+	it.h = c.bucket(it.count).pushBack(e)
+	c.items[key] = it
+	return c.buckets[it.count].value(it.h)
+}
+
+// detach removes h from the ring of count, dropping the ring if empty.
+// minCount is left for the caller to fix.
+func (c *LFU[K, V]) detach(count int, h handle) entry[K, V] {
+	b := c.buckets[count]
+	e := b.remove(h)
+	if b.len() == 0 {
+		delete(c.buckets, count)
+	}
+	return e
+}
+
+// bucket returns the ring of count, creating it if needed.
+func (c *LFU[K, V]) bucket(count int) *ring[entry[K, V]] {
+	b := c.buckets[count]
+	if b == nil {
+		b = &ring[entry[K, V]]{}
+		c.buckets[count] = b
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLFU(t *testing.T) {
+	var evicted []string
+	c := NewLFU(3, WithEvict(func(k string, v int) { evicted = append(evicted, k) }))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	if c.Count("a") != 3 || c.Count("b") != 2 || c.Count("c") != 1 || c.Count("x") != 0 {
+		t.Fatal("counts", c.Count("a"), c.Count("b"), c.Count("c"))
+	}
+	if !c.Put("d", 4) || !slices.Equal(evicted, []string{"c"}) {
+		t.Fatal("Put(d) evicted", evicted)
+	}
+	// d and b tie on use count after this Get; d is older, so it goes.
+	c.Get("d")
+	c.Get("b")
+	c.Put("e", 5)
+	if !slices.Equal(evicted, []string{"c", "d"}) {
+		t.Error("evicted", evicted)
+	}
+	// Peek does not use e, so e is evicted before anything else.
+	if v, ok := c.Peek("e"); !ok || v != 5 || c.Count("e") != 1 {
+		t.Error("Peek(e) =", v, ok)
+	}
+	if c.Put("a", 10) {
+		t.Error("updating a evicted")
+	}
+	if v, _ := c.Get("a"); v != 10 || c.Count("a") != 5 {
+		t.Error("a =", v, "count", c.Count("a"))
+	}
+	c.Put("f", 6)
+	if !slices.Equal(evicted, []string{"c", "d", "e"}) {
+		t.Error("evicted", evicted)
+	}
+	if !c.Remove("f") || c.Remove("f") || c.Len() != 2 || c.Cap() != 3 {
+		t.Error("Remove(f) left Len", c.Len())
+	}
+	if _, ok := c.Get("zz"); ok {
+		t.Error("Get of missing key succeeded")
+	}
+	if s := c.Stats(); s != (Stats{Hits: 6, Misses: 1, Evictions: 3}) {
+		t.Error("Stats() =", s)
+	}
+}
+
+func TestLFURemoveLowest(t *testing.T) {
+	c := NewLFU[int, int](2)
+	c.Put(1, 1)
+	c.Put(2, 2)
+	c.Get(2)
+	c.Remove(1)
+	c.Get(2)
+	c.Put(3, 3)
+	c.Put(4, 4)
+	if _, ok := c.Peek(3); ok {
+		t.Error("3 should have been evicted")
+	}
+	if _, ok := c.Peek(2); !ok {
+		t.Error("2 should still be cached")
+	}
+}
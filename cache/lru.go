@@ -0,0 +1,120 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package cache
+
+// LRU is a cache that evicts the least recently used key when full.
+type LRU[K comparable, V any] struct {
+	capacity int
+	items    map[K]handle
+	order    ring[entry[K, V]] // least recently used first
+	opts     options[K, V]
+	stats    Stats
+}
+
+// NewLRU returns an empty LRU cache holding at most capacity keys. A
+// capacity below 1 is taken as 1.
+func NewLRU[K comparable, V any](capacity int, opts ...Option[K, V]) *LRU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRU[K, V]{
+		capacity: capacity,
+		items:    make(map[K]handle, capacity),
+		opts:     newOptions(opts),
+	}
+}
+
+// Len returns the number of keys in the cache.
+func (c *LRU[K, V]) Len() int { return len(c.items) }
+
+// Cap returns the maximum number of keys in the cache.
+func (c *LRU[K, V]) Cap() int { return c.capacity }
+
+// Stats returns the hit, miss and eviction counts so far.
+func (c *LRU[K, V]) Stats() Stats { return c.stats }
+
+// Get returns the value of key and marks it most recently used, counting
+// a hit, or counts a miss if key is not in the cache.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	h, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.order.moveToBack(h)
+	return c.order.value(h).value, true
+}
+
+// Peek returns the value of key without marking it used or counting the
+// lookup.
+func (c *LRU[K, V]) Peek(key K) (V, bool) {
+	h, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return c.order.value(h).value, true
+}
+
+// Put sets the value of key and marks it most recently used. If key is
+// new and the cache is full, the least recently used key is evicted first
+// and Put returns true.
+func (c *LRU[K, V]) Put(key K, value V) bool {
+	if h, ok := c.items[key]; ok {
+		c.order.value(h).value = value
+		c.order.moveToBack(h)
+		return false
+	}
+	evicted := false
+	if len(c.items) >= c.capacity {
+		h, _ := c.order.front()
+		e := c.order.remove(h)
+		delete(c.items, e.key)
+		c.stats.Evictions++
+		if c.opts.evict != nil {
+			c.opts.evict(e.key, e.value)
+		}
+		evicted = true
+	}
+	c.items[key] = c.order.pushBack(entry[K, V]{key, value})
+	return evicted
+}
+
+// Remove deletes key from the cache and reports whether it was there.
+func (c *LRU[K, V]) Remove(key K) bool {
+	h, ok := c.items[key]
+	if ok {
+		c.order.remove(h)
+		delete(c.items, key)
+	}
+	return ok
+}
+
+// Keys returns the keys in the cache, least recently used first.
+func (c *LRU[K, V]) Keys() []K {
+	keys := make([]K, 0, len(c.items))
+	for h := range c.order.handles() {
+		keys = append(keys, c.order.value(h).key)
+	}
+	return keys
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLRU(t *testing.T) {
+	var evicted []string
+	c := NewLRU(3, WithEvict(func(k string, v int) { evicted = append(evicted, k) }))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatal("Get(a) =", v, ok)
+	}
+	if c.Put("d", 4) != true || !slices.Equal(evicted, []string{"b"}) {
+		t.Fatal("Put(d) evicted", evicted)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b still cached")
+	}
+	if !slices.Equal(c.Keys(), []string{"c", "a", "d"}) {
+		t.Error("Keys() =", c.Keys())
+	}
+	// Peek does not mark c used, so it is evicted next.
+	if v, ok := c.Peek("c"); !ok || v != 3 {
+		t.Error("Peek(c) =", v, ok)
+	}
+	if c.Put("a", 10) {
+		t.Error("updating a evicted")
+	}
+	c.Put("e", 5)
+	if !slices.Equal(evicted, []string{"b", "c"}) {
+		t.Error("evicted", evicted)
+	}
+	if v, _ := c.Peek("a"); v != 10 {
+		t.Error("a =", v)
+	}
+	if !c.Remove("a") || c.Remove("a") || c.Len() != 2 || c.Cap() != 3 {
+		t.Error("Remove(a) left Len", c.Len())
+	}
+	if s := c.Stats(); s != (Stats{Hits: 1, Misses: 1, Evictions: 2}) {
+		t.Error("Stats() =", s)
+	}
+}
+
+func TestLRUChurn(t *testing.T) {
+	c := NewLRU[int, int](100)
+	for i := 0; i < 10000; i++ {
+		c.Put(i%150, i)
+		c.Get(i % 37)
+	}
+	if c.Len() != 100 || len(c.Keys()) != 100 {
+		t.Fatal("Len", c.Len(), "Keys", len(c.Keys()))
+	}
+	for _, k := range c.Keys() {
+		if v, ok := c.Peek(k); !ok || v%150 != k {
+			t.Fatalf("key %d holds %d", k, v)
+		}
+	}
+	if len(c.order.buf) > 256 {
+		t.Error("ring buffer grew to", len(c.order.buf))
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package cache
+
+import "iter"
+
+// minSize is the smallest ring buffer, a power of 2.
+const minSize = 16
+
+// hole marks a ring position whose element was moved or removed.
+const hole = -1
+
+// handle identifies an element of a ring for as long as it is in it. It
+// is an index into the ring's nodes, so it is not changed by moving the
+// element, nor by the ring growing or compacting its buffer.
+type handle int32
+
+type node[T any] struct {
+	value T
+	pos   uint64 // absolute position in the ring
+}
+
+// ring is a FIFO of values whose elements can be removed or moved to the
+// back in constant time, through handles. The buffer holds handles at
+// absolute positions first to end, masked as in deque; removing or moving
+// an element leaves a hole, which is skipped at the front and dropped
+// when the buffer is resized.
+type ring[T any] struct {
+	buf   []handle
+	first uint64
+	end   uint64
+	live  int
+	nodes []node[T]
+	free  []handle
+}
+
+// len returns the number of elements in the ring.
+func (r *ring[T]) len() int { return r.live }
+
+// value returns a pointer to the value of the element h.
+func (r *ring[T]) value(h handle) *T { return &r.nodes[h].value }
+
+// pushBack adds v at the back of the ring and returns its handle.
+func (r *ring[T]) pushBack(v T) handle {
+	var h handle
+	if n := len(r.free); n > 0 {
+		h = r.free[n-1]
+		r.free = r.free[:n-1]
+		r.nodes[h].value = v
+	} else {
+		h = handle(len(r.nodes))
+		r.nodes = append(r.nodes, node[T]{value: v})
+	}
+	r.place(h)
+	r.live++
+	return h
+}
+
+// moveToBack moves the element h to the back of the ring.
+func (r *ring[T]) moveToBack(h handle) {
+	pos := r.nodes[h].pos
+	if pos == r.end-1 {
+		return
+	}
+	r.buf[pos&r.mask()] = hole
+	r.place(h)
+	r.trim()
+}
+
+// remove removes the element h from the ring and returns its value. The
+// handle may be reused by a later pushBack.
+func (r *ring[T]) remove(h handle) T {
+	n := &r.nodes[h]
+	v := n.value
+	var zero T
+	n.value = zero
+	r.buf[n.pos&r.mask()] = hole
+	r.free = append(r.free, h)
+	r.live--
+	r.trim()
+	if len(r.buf) > minSize && r.live<<2 <= len(r.buf) {
+		r.resize()
+	}
+	return v
+}
+
+// front returns the handle of the oldest element, if there is one.
+func (r *ring[T]) front() (handle, bool) {
+	if r.live == 0 {
+		return hole, false
+	}
+	return r.buf[r.first&r.mask()], true
+}
+
+// handles returns an iterator over the handles of the elements, oldest
+// first.
+func (r *ring[T]) handles() iter.Seq[handle] {
+	return func(yield func(handle) bool) {
+		for p := r.first; p < r.end; p++ {
+			if h := r.buf[p&r.mask()]; h != hole && !yield(h) {
+				return
+			}
+		}
+	}
+}
+
+// place puts h at the end of the ring, resizing the buffer if it is full.
+func (r *ring[T]) place(h handle) {
+	if len(r.buf) == 0 {
+		r.buf = make([]handle, minSize)
+	} else if r.end-r.first == uint64(len(r.buf)) {
+		r.resize()
+	}
+	r.buf[r.end&r.mask()] = h
+	r.nodes[h].pos = r.end
+	r.end++
+}
+
+// trim advances first past the holes at the front.
+func (r *ring[T]) trim() {
+	for r.first < r.end && r.buf[r.first&r.mask()] == hole {
+		r.first++
+	}
+}
+
+// resize copies the live elements, dropping the holes, into a buffer
+// twice their number and updates their positions. Positions start again
+// at zero, but handles stay the same.
+func (r *ring[T]) resize() {
+	size := minSize
+	for size < r.live<<1 {
+		size <<= 1
+	}
+	buf := make([]handle, size)
+	n := 0
+	for p := r.first; p < r.end; p++ {
+		if h := r.buf[p&r.mask()]; h != hole {
+			buf[n] = h
+			r.nodes[h].pos = uint64(n)
+			n++
+		}
+	}
+	r.buf = buf
+	r.first = 0
+	r.end = uint64(n)
+}
+
+func (r *ring[T]) mask() uint64 { return uint64(len(r.buf) - 1) }
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+)
+
+func contents(r *ring[int]) []int {
+	var values []int
+	for h := range r.handles() {
+		values = append(values, *r.value(h))
+	}
+	return values
+}
+
+func TestRingHandlesSurviveResize(t *testing.T) {
+	var r ring[int]
+	handles := make([]handle, 100)
+	for i := range handles {
+		handles[i] = r.pushBack(i)
+	}
+	if len(r.buf) != 128 {
+		t.Fatal("buffer size", len(r.buf))
+	}
+	// Moving every even element to the back leaves holes that the ring
+	// drops when it grows.
+	for i := 0; i < 100; i += 2 {
+		r.moveToBack(handles[i])
+	}
+	for i, h := range handles {
+		if *r.value(h) != i {
+			t.Fatalf("handle %d holds %d, expected %d", h, *r.value(h), i)
+		}
+	}
+	// Removing most elements compacts the buffer.
+	for i := 0; i < 90; i++ {
+		r.remove(handles[i])
+	}
+	if len(r.buf) > 32 || r.len() != 10 {
+		t.Error("after removals buffer size", len(r.buf), "len", r.len())
+	}
+	want := []int{91, 93, 95, 97, 99, 90, 92, 94, 96, 98}
+	if got := contents(&r); !slices.Equal(got, want) {
+		t.Error("contents", got, "expected", want)
+	}
+	for _, i := range want {
+		if *r.value(handles[i]) != i {
+			t.Errorf("handle of %d holds %d after compaction", i, *r.value(handles[i]))
+		}
+	}
+	if h, ok := r.front(); !ok || *r.value(h) != 91 {
+		t.Error("front() =", h, ok)
+	}
+}
+
+func TestRingReuse(t *testing.T) {
+	var r ring[int]
+	a := r.pushBack(1)
+	b := r.pushBack(2)
+	r.remove(a)
+	if c := r.pushBack(3); c != a {
+		t.Error("handle not reused, got", c)
+	}
+	r.moveToBack(b)
+	r.moveToBack(b)
+	if got := contents(&r); !slices.Equal(got, []int{3, 2}) {
+		t.Error("contents", got)
+	}
+	// Moving one element many times must not grow the buffer.
+	for i := 0; i < 1000; i++ {
+		r.moveToBack(a)
+		r.moveToBack(b)
+	}
+	if len(r.buf) != minSize {
+		t.Error("buffer grew to", len(r.buf))
+	}
+	r.remove(a)
+	r.remove(b)
+	if _, ok := r.front(); ok || r.len() != 0 {
+		t.Error("ring not empty")
+	}
+}
@@ -19,27 +19,63 @@
 //
 
 package circularbuffer
- 
 
-type circularbuffer struct {
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+)
+
+// CircularBuffer is a fixed size ring of values of type T. Once the
+// buffer is full each Push drops the oldest value.
+type CircularBuffer[T any] struct {
 	Len	int
 	head	int
-	buffer	[]interface{}
+	buffer	[]T
+	evict	func(T)
+	pushed	[]func(value, old T)
+}
+
+// Option configures a CircularBuffer when passed to New.
+type Option[T any] func(*CircularBuffer[T])
+
+// WithEvict registers f to be called with the value that each Push
+// drops from the buffer, before the new value is stored.
+func WithEvict[T any](f func(T)) Option[T] {
+	return func(c *CircularBuffer[T]) { c.evict = f }
+}
+
+// WithPush registers f to be called after each Push with the value
+// pushed and the value it dropped. Several functions may be registered
+// and are called in order, so helpers computing over the buffer see it
+// already updated.
+func WithPush[T any](f func(value, old T)) Option[T] {
+	return func(c *CircularBuffer[T]) { c.pushed = append(c.pushed, f) }
+}
+
+// WithValues pushes vals in order once the buffer has been filled with
+// the initial value, so the last len(vals) positions hold them.
+func WithValues[T any](vals ...T) Option[T] {
+	return func(c *CircularBuffer[T]) {
+		for _, v := range vals { c.Push(v) }
+	}
 }
 
 // We allocate a circularbuffer structure that basically is a fixed size
-// array of pointers (interface{}). We give the option of specifying the
+// array of values of type T. We give the option of specifying the
 // initial size of the array and using make with cap.
-// The non-exposed head and full are actually pointers as indexes.
-func New(size int, initval interface{}) *circularbuffer {
-	c 	 := &circularbuffer{head: 0, Len: size }
-	c.buffer =  make([]interface{},size,size)
-        for i:= range c.buffer { c.buffer[i] = initval }
+// The non-exposed head is actually a pointer as index.
+// Options are applied in order after the buffer is filled with initval.
+func New[T any](size int, initval T, opts ...Option[T]) *CircularBuffer[T] {
+	c 	 := &CircularBuffer[T]{head: 0, Len: size }
+	c.buffer =  make([]T,size,size)
+	for i:= range c.buffer { c.buffer[i] = initval }
+	for _, opt := range opts { opt(c) }
 	return c
 }
 
 // Length of buffer. Not really needed since we export Len.
-func (c *circularbuffer) Length() int { return c.Len }
+func (c *CircularBuffer[T]) Length() int { return c.Len }
 
 // This sets a particular value of the buffer array. The function 
 // returns the previous value stored in the idx position.
@@ -47,7 +83,7 @@ func (c *circularbuffer) Length() int { return c.Len }
 // extreme caution. The preferred way is to use Push.
 // The idx argument can be positive or negative, always considered
 // from the start of the buffer pointed to by the value of head.
-func (c *circularbuffer) Set(idx int, value interface{}) interface{} {
+func (c *CircularBuffer[T]) Set(idx int, value T) T {
 	if idx <= -c.Len {idx = idx + c.Len * (-idx/c.Len)}
 	where := (c.head + idx + c.Len) % c.Len
 	oldvalue := c.buffer[where]
@@ -57,14 +93,14 @@ func (c *circularbuffer) Set(idx int, value interface{}) interface{} {
 
 // This function retrieves a particular value of the buffer
 // at idx relative to head.
-func (c *circularbuffer) Get(idx int) interface{} {
-        if idx <= -c.Len {idx = idx + c.Len * (-idx/c.Len)}
-        where := (c.head + idx + c.Len) % c.Len
-        return c.buffer[where]
+func (c *CircularBuffer[T]) Get(idx int) T {
+	if idx <= -c.Len {idx = idx + c.Len * (-idx/c.Len)}
+	where := (c.head + idx + c.Len) % c.Len
+	return c.buffer[where]
 }
 
 // This function initializes the whole buffer to a set value
-func (c *circularbuffer) Init(initval interface{}) {
+func (c *CircularBuffer[T]) Init(initval T) {
 	for i:= range c.buffer { c.buffer[i] = initval }
 }
 
@@ -74,22 +110,91 @@ func (c *circularbuffer) Init(initval interface{}) {
 // exhausted, the first value is dropped and replaced
 // with the pushed new value. The head of buffer is 
 // incremented and wrapped around if necessary.
-func (c *circularbuffer) Push(value interface{}) interface{} {
+func (c *CircularBuffer[T]) Push(value T) T {
 	// buffer is full, so head should take the new value
 	oldvalue := c.buffer[c.head]
+	if c.evict != nil { c.evict(oldvalue) }
 	c.buffer[c.head] = value
 	c.head = (c.head + 1) % c.Len
-        return oldvalue
+	for _, f := range c.pushed { f(value, oldvalue) }
+	return oldvalue
 }
 
-// Get the ordered list of values based on the fullent state.
-func (c *circularbuffer) GetValues() []interface{} {
-	return append(c.buffer[c.head:c.Len], c.buffer[0:c.head]...)
+// Get the ordered list of values, oldest first, in a new slice.
+func (c *CircularBuffer[T]) GetValues() []T {
+	values := make([]T, 0, c.Len)
+	values = append(values, c.buffer[c.head:c.Len]...)
+	return append(values, c.buffer[0:c.head]...)
 }
 
 // Execute a functions for each element
-func (c *circularbuffer) Do(f func(interface{})) {
+func (c *CircularBuffer[T]) Do(f func(T)) {
 	for _, value := range c.buffer { f(value) }
 }
 
+// All returns an iterator over the index and value pairs of the buffer
+// in logical order, oldest (index 0) to newest (index Len-1).
+func (c *CircularBuffer[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := 0; i < c.Len; i++ {
+			if !yield(i, c.buffer[(c.head + i) % c.Len]) { return }
+		}
+	}
+}
+
+// Backward returns an iterator over the index and value pairs of the
+// buffer, newest to oldest.
+func (c *CircularBuffer[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := c.Len - 1; i >= 0; i-- {
+			if !yield(i, c.buffer[(c.head + i) % c.Len]) { return }
+		}
+	}
+}
+
+// Values returns an iterator over the values of the buffer, oldest
+// to newest.
+func (c *CircularBuffer[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < c.Len; i++ {
+			if !yield(c.buffer[(c.head + i) % c.Len]) { return }
+		}
+	}
+}
+
+// jsonBuffer is the JSON encoding of a CircularBuffer.
+type jsonBuffer[T any] struct {
+	Capacity	int	`json:"capacity"`
+	Values		[]T	`json:"values"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the buffer as its
+// capacity and its values in logical order, oldest first.
+func (c CircularBuffer[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonBuffer[T]{Capacity: c.Len, Values: c.GetValues()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The buffer takes the
+// capacity and values of the encoding, with head back at the oldest
+// value; if there are fewer values than the capacity, the oldest
+// positions hold the zero value of T. A missing capacity is taken from
+// the number of values, and must come out at least 1. Options given to
+// New are kept.
+func (c *CircularBuffer[T]) UnmarshalJSON(data []byte) error {
+	var j jsonBuffer[T]
+	if err := json.Unmarshal(data, &j); err != nil { return err }
+	if j.Capacity == 0 { j.Capacity = len(j.Values) }
+	if j.Capacity < 1 {
+		return fmt.Errorf("circularbuffer: invalid capacity %d", j.Capacity)
+	}
+	if len(j.Values) > j.Capacity {
+		return fmt.Errorf("circularbuffer: %d values exceed capacity %d", len(j.Values), j.Capacity)
+	}
+	c.buffer = make([]T, j.Capacity)
+	copy(c.buffer[j.Capacity-len(j.Values):], j.Values)
+	c.Len = j.Capacity
+	c.head = 0
+	return nil
+}
+
 // end
@@ -22,11 +22,13 @@ package circularbuffer
  
 
 import	(
+	"encoding/json"
 	"fmt"
+	"slices"
 	"testing"
 	)
 
-func dump(c *circularbuffer) {
+func dump(c *CircularBuffer[int]) {
 	if c == nil {
 		fmt.Println("empty buffer")
 		return
@@ -46,7 +48,7 @@ func TestInit(t *testing.T) {
 	cbuf.Init(6)
 	total := 0
 	for _, value := range cbuf.GetValues() {
-		total += value.(int)
+		total += value
 	}
 	if total == 6 * 30 { 
 		fmt.Printf("Match init\n")
@@ -61,7 +63,7 @@ func TestMain(t *testing.T) {
         for i := 1; i <= 8 ; i++ {
                 old := cbuf.Push(i)
 		dump(cbuf)
-                fmt.Printf("iter %d last value: %d (old value: %v)  %+v \n", i, cbuf.Get(-1).(int), old, cbuf.GetValues())
+                fmt.Printf("iter %d last value: %d (old value: %v)  %+v \n", i, cbuf.Get(-1), old, cbuf.GetValues())
         }
         fmt.Printf("%#v \n", cbuf.GetValues())
 }
@@ -70,7 +72,7 @@ func TestGetSet(t *testing.T) {
 	cbuf := New(10,0)
 	for i := -10; i < 10; i++ {
 		old := cbuf.Set(i, i*i)
-		fmt.Printf("Setting %d with %d (old %d) for %v\n",i,i*i, old.(int), cbuf.GetValues())
+		fmt.Printf("Setting %d with %d (old %d) for %v\n",i,i*i, old, cbuf.GetValues())
 	}
 }
 
@@ -89,10 +91,117 @@ func TestDoFunction(t *testing.T) {
 	for i := 1; i < 20; i++ { cbuf.Set(i, i*i)}
 	n := 0
 	s := 0
-	cbuf.Do( func(p interface{}) {
+	cbuf.Do( func(p int) {
 		n++
-		if p != nil { s += p.(int) }
+		s += p
 		})
 	fmt.Printf("N and S: %d %d %v\n",n,s,cbuf.GetValues())
 }
 
+
+
+type measure struct {
+	msgNum		int
+	isAlert		bool
+}
+
+func TestTypedMeasure(t *testing.T) {
+	var window *CircularBuffer[measure] = New(5, measure{})
+	for i := 1; i <= 8; i++ {
+		window.Push(measure{msgNum: i, isAlert: i%3 == 0})
+	}
+	values := window.GetValues()
+	for i, m := range values {
+		if m.msgNum != i+4 { t.Errorf("position %d has msgNum %d, expected %d", i, m.msgNum, i+4) }
+	}
+	if !window.Get(-3).isAlert { t.Error("msgNum 6 should be an alert") }
+}
+
+func TestOptions(t *testing.T) {
+	var evicted []int
+	cbuf := New(3, -1,
+		WithEvict(func(old int) { evicted = append(evicted, old) }),
+		WithValues(1, 2))
+	if got := cbuf.GetValues(); got[0] != -1 || got[1] != 1 || got[2] != 2 {
+		t.Errorf("WithValues gave %v", got)
+	}
+	cbuf.Push(3)
+	cbuf.Push(4)
+	// two evictions from WithValues, two from Push
+	if len(evicted) != 4 || evicted[2] != -1 || evicted[3] != 1 {
+		t.Errorf("evicted %v", evicted)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	cbuf := New(5, 0)
+	for i := 1; i <= 8; i++ { cbuf.Push(i) }
+	if got := slices.Collect(cbuf.Values()); !slices.Equal(got, cbuf.GetValues()) {
+		t.Errorf("Values() collected %v, expected %v", got, cbuf.GetValues())
+	}
+	for i, v := range cbuf.All() {
+		if v != cbuf.Get(i) { t.Errorf("All() gave %d at %d, expected %d", v, i, cbuf.Get(i)) }
+	}
+	var newest []int
+	for _, v := range cbuf.Backward() {
+		if newest = append(newest, v); len(newest) == 3 { break }
+	}
+	if !slices.Equal(newest, []int{8, 7, 6}) { t.Errorf("Backward() visited %v", newest) }
+	if slices.Max(slices.Collect(cbuf.Values())) != 8 { t.Error("wrong max over Values()") }
+}
+
+func TestWithPush(t *testing.T) {
+	var pushed, dropped []int
+	cbuf := New(2, 0,
+		WithPush(func(value, old int) { pushed = append(pushed, value) }),
+		WithPush(func(value, old int) { dropped = append(dropped, old) }))
+	for i := 1; i <= 3; i++ { cbuf.Push(i) }
+	if !slices.Equal(pushed, []int{1, 2, 3}) || !slices.Equal(dropped, []int{0, 0, 1}) {
+		t.Errorf("pushed %v dropped %v", pushed, dropped)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	c := New(4, 0)
+	for i := 1; i <= 6; i++ { c.Push(i) }
+	b, err := json.Marshal(c)
+	if err != nil { t.Fatal(err) }
+	if string(b) != `{"capacity":4,"values":[3,4,5,6]}` {
+		t.Fatal("json.Marshal gave", string(b))
+	}
+	var evicted []int
+	r := New(2, 0, WithEvict(func(v int) { evicted = append(evicted, v) }))
+	if err := json.Unmarshal(b, r); err != nil { t.Fatal(err) }
+	if r.Len != 4 || !slices.Equal(r.GetValues(), []int{3, 4, 5, 6}) {
+		t.Error("json.Unmarshal gave", r.GetValues())
+	}
+	r.Push(7)
+	if !slices.Equal(evicted, []int{3}) {
+		t.Error("evict callback lost after json.Unmarshal, got", evicted)
+	}
+	if err := json.Unmarshal([]byte(`{"capacity":3,"values":[1]}`), r); err != nil { t.Fatal(err) }
+	if !slices.Equal(r.GetValues(), []int{0, 0, 1}) {
+		t.Error("short values gave", r.GetValues())
+	}
+	if err := json.Unmarshal([]byte(`{"capacity":1,"values":[1,2]}`), r); err == nil {
+		t.Error("expected error for values exceeding capacity")
+	}
+	for _, bad := range []string{`{"capacity":0,"values":[]}`, `{"values":[]}`, `{"capacity":-2}`} {
+		if err := json.Unmarshal([]byte(bad), r); err == nil {
+			t.Error("expected error for", bad)
+		}
+	}
+	r.Push(9)
+}
+
+func TestJSONByValue(t *testing.T) {
+	c := New(2, 0, WithValues(1, 2))
+	b, err := json.Marshal(*c)
+	if err != nil || string(b) != `{"capacity":2,"values":[1,2]}` {
+		t.Errorf("json.Marshal by value gave %s, %v", b, err)
+	}
+	b, err = json.Marshal(struct{ C CircularBuffer[int] }{*c})
+	if err != nil || string(b) != `{"C":{"capacity":2,"values":[1,2]}}` {
+		t.Errorf("json.Marshal of struct field gave %s, %v", b, err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+
+package circularbuffer
+
+import "sync"
+
+// SafeCircularBuffer is a CircularBuffer that may be used by several
+// goroutines at once. Get, GetValues and Do take a read lock, so
+// readers do not block each other.
+type SafeCircularBuffer[T any] struct {
+	mu	sync.RWMutex
+	c	*CircularBuffer[T]
+}
+
+// NewSafe allocates a SafeCircularBuffer the same way New does.
+func NewSafe[T any](size int, initval T, opts ...Option[T]) *SafeCircularBuffer[T] {
+	return &SafeCircularBuffer[T]{c: New(size, initval, opts...)}
+}
+
+// Length of buffer.
+func (s *SafeCircularBuffer[T]) Length() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.c.Len
+}
+
+// Set a value at idx relative to head, returning the previous one.
+func (s *SafeCircularBuffer[T]) Set(idx int, value T) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Set(idx, value)
+}
+
+// Get the value at idx relative to head.
+func (s *SafeCircularBuffer[T]) Get(idx int) T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.c.Get(idx)
+}
+
+// Init sets the whole buffer to initval.
+func (s *SafeCircularBuffer[T]) Init(initval T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Init(initval)
+}
+
+// Push adds value to the buffer and returns the value it dropped.
+func (s *SafeCircularBuffer[T]) Push(value T) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Push(value)
+}
+
+// PushIf pushes value only if f returns true for the newest value in
+// the buffer, as one operation. It returns the dropped value and true
+// if value was pushed.
+func (s *SafeCircularBuffer[T]) PushIf(f func(newest T) bool, value T) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !f(s.c.Get(-1)) {
+		var zero T
+		return zero, false
+	}
+	return s.c.Push(value), true
+}
+
+// Get the ordered list of values, oldest first, in a new slice.
+func (s *SafeCircularBuffer[T]) GetValues() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.c.GetValues()
+}
+
+// Execute a function for each element under the read lock. f must
+// not use s.
+func (s *SafeCircularBuffer[T]) Do(f func(T)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.c.Do(f)
+}
+
+// Update calls f with the underlying buffer under the write lock, so
+// any sequence of operations done by f is atomic.
+func (s *SafeCircularBuffer[T]) Update(f func(c *CircularBuffer[T])) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.c)
+}
@@ -0,0 +1,69 @@
+package circularbuffer
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSafeConcurrent(t *testing.T) {
+	cbuf := NewSafe(16, 0)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= 1000; i++ { cbuf.Push(i) }
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				n := 0
+				cbuf.Do(func(int) { n++ })
+				if n != 16 { t.Error("Do visited", n, "values") }
+				cbuf.GetValues()
+			}
+		}()
+	}
+	wg.Wait()
+	for _, v := range cbuf.GetValues() {
+		if v < 1 || v > 1000 { t.Error("unexpected value", v) }
+	}
+}
+
+func TestSafePushIf(t *testing.T) {
+	cbuf := NewSafe(4, 0)
+	var wg sync.WaitGroup
+	// Only one goroutine can push each next value after the newest.
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= 100; i++ {
+				want := i - 1
+				cbuf.PushIf(func(newest int) bool { return newest == want }, i)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := cbuf.GetValues(); got[0] != 97 || got[3] != 100 {
+		t.Errorf("PushIf sequence ended with %v", got)
+	}
+}
+
+func TestSafeLengthDuringUpdate(t *testing.T) {
+	cbuf := NewSafe(4, 0)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			cbuf.Update(func(c *CircularBuffer[int]) {
+				if err := c.UnmarshalJSON([]byte(`{"values":[1,2,3,4,5,6,7,8]}`)); err != nil { t.Error(err) }
+			})
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if n := cbuf.Length(); n != 4 && n != 8 { t.Error("Length() =", n) }
+	}
+	wg.Wait()
+}
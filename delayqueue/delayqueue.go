@@ -0,0 +1,219 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package delayqueue provides a queue whose items become visible only
+// once their ready time has come, for work such as retries with backoff.
+// Items are kept in a heap ordered by ready time, first in, first out
+// among equal times; Take waits until the earliest one is due.
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gus-maurizio/structures/internal/broadcast"
+	"github.com/gus-maurizio/structures/pqueue"
+)
+
+// ErrClosed is returned by Put once the queue is closed, and by Take once
+// the queue is closed and empty.
+var ErrClosed = errors.New("delayqueue: queue closed")
+
+// Item is a value in a Queue and the handle for cancelling or
+// rescheduling it.
+type Item[T any] struct {
+	value T
+	at    time.Time
+	h     *pqueue.Item[*Item[T]]
+}
+
+// Value returns the value of the item.
+func (it *Item[T]) Value() T { return it.value }
+
+// ReadyAt returns the time at which the item becomes visible.
+func (it *Item[T]) ReadyAt() time.Time { return it.at }
+
+// Queue is a delay queue safe for use by several goroutines.
+type Queue[T any] struct {
+	clock Clock
+
+	mu     sync.Mutex
+	items  *pqueue.Heap[*Item[T]]
+	closed bool
+	// changed is broadcast when the earliest item may have changed or the
+	// queue is closed.
+	changed broadcast.Signal
+}
+
+// Option configures a Queue when passed to New.
+type Option[T any] func(*Queue[T])
+
+// WithClock makes the queue read the time and wait through c instead of
+// the time package.
+func WithClock[T any](c Clock) Option[T] {
+	return func(q *Queue[T]) { q.clock = c }
+}
+
+// New returns an empty queue.
+func New[T any](opts ...Option[T]) *Queue[T] {
+	q := &Queue[T]{
+		clock: realClock{},
+		items: pqueue.New(func(a, b *Item[T]) bool { return a.at.Before(b.at) },
+			pqueue.WithStable[*Item[T]]()),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Len returns the number of items in the queue, due or not.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
+
+// Next returns the ready time of the earliest item.
+func (q *Queue[T]) Next() (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	it, ok := q.items.Peek()
+	if !ok {
+		return time.Time{}, false
+	}
+	return it.at, true
+}
+
+// Put adds v to the queue, ready once delay has passed.
+func (q *Queue[T]) Put(v T, delay time.Duration) (*Item[T], error) {
+	return q.PutAt(v, q.clock.Now().Add(delay))
+}
+
+// PutAt adds v to the queue, ready at the given time.
+func (q *Queue[T]) PutAt(v T, at time.Time) (*Item[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+	it := &Item[T]{value: v, at: at}
+	it.h = q.items.Push(it)
+	q.changed.Broadcast()
+	return it, nil
+}
+
+// Cancel removes it from the queue and reports whether it was there.
+func (q *Queue[T]) Cancel(it *Item[T]) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.items.Remove(it.h) {
+		return false
+	}
+	q.changed.Broadcast()
+	return true
+}
+
+// Reschedule makes it ready once delay has passed from now, and reports
+// whether it was still in the queue.
+func (q *Queue[T]) Reschedule(it *Item[T], delay time.Duration) bool {
+	return q.RescheduleAt(it, q.clock.Now().Add(delay))
+}
+
+// RescheduleAt makes it ready at the given time, and reports whether it
+// was still in the queue.
+func (q *Queue[T]) RescheduleAt(it *Item[T], at time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.items.Contains(it.h) {
+		return false
+	}
+	it.at = at
+	q.items.Fix(it.h)
+	q.changed.Broadcast()
+	return true
+}
+
+// TryTake removes and returns the earliest item if it is due, without
+// waiting.
+func (q *Queue[T]) TryTake() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	it, ok := q.items.Peek()
+	if !ok || it.at.After(q.clock.Now()) {
+		var zero T
+		return zero, false
+	}
+	q.items.Pop()
+	return it.value, true
+}
+
+// Take removes and returns the earliest item, waiting until it is due.
+// Items still queued when the queue is closed are returned as they fall
+// due; after that Take returns ErrClosed. If ctx is done first it returns
+// ctx.Err().
+func (q *Queue[T]) Take(ctx context.Context) (T, error) {
+	var zero T
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		var due <-chan time.Time
+		it, ok := q.items.Peek()
+		if ok {
+			wait := it.at.Sub(q.clock.Now())
+			if wait <= 0 {
+				q.items.Pop()
+				return it.value, nil
+			}
+			due = q.clock.After(wait)
+		} else if q.closed {
+			return zero, ErrClosed
+		}
+		if err := q.wait(ctx, q.changed.Wait(), due); err != nil {
+			return zero, err
+		}
+	}
+}
+
+// Close stops the queue from accepting items and wakes every waiting
+// Take. Closing a closed queue has no effect.
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.changed.Broadcast()
+}
+
+// wait releases the lock until changed is closed, due fires or ctx is
+// done, and takes it again before returning.
+func (q *Queue[T]) wait(ctx context.Context, changed <-chan struct{}, due <-chan time.Time) error {
+	q.mu.Unlock()
+	defer q.mu.Lock()
+	select {
+	case <-changed:
+		return nil
+	case <-due:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
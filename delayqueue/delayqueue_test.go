@@ -0,0 +1,166 @@
+package delayqueue
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newManual() (*Queue[string], *ManualClock) {
+	c := NewManualClock(epoch)
+	return New(WithClock[string](c)), c
+}
+
+// blocked waits until n channels from c.After are pending.
+func blocked(c *ManualClock, n int) {
+	for c.Waiters() < n {
+		runtime.Gosched()
+	}
+}
+
+func take(q *Queue[string]) <-chan string {
+	ch := make(chan string, 1)
+	go func() {
+		v, err := q.Take(context.Background())
+		if err != nil {
+			v = err.Error()
+		}
+		ch <- v
+	}()
+	return ch
+}
+
+func TestTryTakeOrder(t *testing.T) {
+	q, c := newManual()
+	q.Put("c", 3*time.Second)
+	q.Put("a", time.Second)
+	q.Put("b", time.Second)
+	q.Put("now", 0)
+	if next, _ := q.Next(); !next.Equal(epoch) {
+		t.Error("Next() =", next)
+	}
+	var got []string
+	for _, step := range []time.Duration{0, time.Second, 2 * time.Second} {
+		c.Advance(step)
+		for {
+			v, ok := q.TryTake()
+			if !ok {
+				break
+			}
+			got = append(got, v)
+		}
+		got = append(got, "|")
+	}
+	if want := "now|ab|c|"; strings.Join(got, "") != want {
+		t.Errorf("took %s, expected %s", strings.Join(got, ""), want)
+	}
+	if q.Len() != 0 {
+		t.Error("Len() =", q.Len())
+	}
+}
+
+func TestTakeWaits(t *testing.T) {
+	q, c := newManual()
+	q.Put("x", time.Minute)
+	ch := take(q)
+	blocked(c, 1)
+	c.Advance(59 * time.Second)
+	select {
+	case v := <-ch:
+		t.Fatal("Take returned early with", v)
+	case <-time.After(10 * time.Millisecond):
+	}
+	c.Advance(time.Second)
+	if v := <-ch; v != "x" {
+		t.Error("Take returned", v)
+	}
+}
+
+func TestTakeSeesEarlierPut(t *testing.T) {
+	q, c := newManual()
+	ch := take(q)
+	runtime.Gosched()
+	q.Put("late", time.Hour)
+	blocked(c, 1)
+	q.Put("soon", time.Second)
+	blocked(c, 2)
+	c.Advance(time.Second)
+	if v := <-ch; v != "soon" {
+		t.Error("Take returned", v)
+	}
+}
+
+func TestCancelReschedule(t *testing.T) {
+	q, c := newManual()
+	a, _ := q.Put("a", time.Second)
+	b, _ := q.Put("b", 2*time.Second)
+	ch := take(q)
+	blocked(c, 1)
+	if !q.Cancel(a) || q.Cancel(a) || q.Reschedule(a, 0) {
+		t.Fatal("Cancel of a misreported")
+	}
+	if !q.Reschedule(b, 5*time.Second) || !b.ReadyAt().Equal(epoch.Add(5*time.Second)) {
+		t.Fatal("Reschedule of b misreported, ready at", b.ReadyAt())
+	}
+	c.Advance(4 * time.Second)
+	select {
+	case v := <-ch:
+		t.Fatal("Take returned early with", v)
+	case <-time.After(10 * time.Millisecond):
+	}
+	c.Advance(time.Second)
+	if v := <-ch; v != "b" || b.Value() != "b" {
+		t.Error("Take returned", v)
+	}
+	if q.Cancel(b) {
+		t.Error("Cancel of taken item succeeded")
+	}
+}
+
+func TestTakeContext(t *testing.T) {
+	q, _ := newManual()
+	q.Put("x", time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Take(ctx); err != context.DeadlineExceeded {
+		t.Error("Take returned", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	q, c := newManual()
+	q.Put("x", time.Second)
+	ch := take(q)
+	blocked(c, 1)
+	q.Close()
+	if _, err := q.Put("y", 0); err != ErrClosed {
+		t.Error("Put after Close returned", err)
+	}
+	c.Advance(time.Second)
+	if v := <-ch; v != "x" {
+		t.Error("Take after Close returned", v)
+	}
+	if v := <-take(q); v != ErrClosed.Error() {
+		t.Error("Take on closed empty queue returned", v)
+	}
+	q.Close()
+}
+
+func TestRealClock(t *testing.T) {
+	q := New[int]()
+	start := time.Now()
+	q.Put(1, 20*time.Millisecond)
+	q.Put(2, 5*time.Millisecond)
+	for _, want := range []int{2, 1} {
+		if v, err := q.Take(context.Background()); v != want || err != nil {
+			t.Fatal("Take returned", v, err)
+		}
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("Take returned before the item was due")
+	}
+}
@@ -20,6 +20,11 @@
 
 package deque
 
+import (
+	"encoding/json"
+	"iter"
+)
+
 // Power of 2 for bitwise modulus: x % n == x & (n - 1).
 const minSize = 64
 
@@ -118,6 +123,71 @@ func (q *Deque) At(i int) interface{} {
 	return q.buffer[(q.first+i)&(len(q.buffer)-1)]
 }
 
+// Insert inserts an element before the element at index i, so that
+// At(i) returns it afterwards. Insert(0,e) is the same as PushFirst(e)
+// and Insert(Len(),e) the same as PushLast(e). The elements between i
+// and the nearest end of the deque are shifted one place in the buffer.
+// Like At(), an index out of range is ignored and nothing is inserted.
+func (q *Deque) Insert(i int, dequeitem interface{}) {
+	if i < 0 || i > q.size { return }
+	if i == 0 {
+		q.PushFirst(dequeitem)
+		return
+	}
+	if i == q.size {
+		q.PushLast(dequeitem)
+		return
+	}
+	q.resizeIfNeeded()
+
+	modBits := len(q.buffer) - 1
+	if i < q.size/2 {
+		// Shift the first i elements one place towards first.
+		q.first = q.prev(q.first)
+		for k := 0; k < i; k++ {
+			q.buffer[(q.first+k)&modBits] = q.buffer[(q.first+k+1)&modBits]
+		}
+	} else {
+		// Shift the elements from i one place towards last.
+		for k := q.size; k > i; k-- {
+			q.buffer[(q.first+k)&modBits] = q.buffer[(q.first+k-1)&modBits]
+		}
+		q.last = q.next(q.last)
+	}
+	q.buffer[(q.first+i)&modBits] = dequeitem
+	q.size++
+}
+
+// Remove removes and returns the element at index i. Remove(0) is the
+// same as PopFirst() and Remove(Len()-1) the same as PopLast(). The
+// elements between i and the nearest end of the deque are shifted one
+// place in the buffer. Like At(), an index out of range returns nil.
+func (q *Deque) Remove(i int) interface{} {
+	if i < 0 || i >= q.size { return nil }
+
+	modBits := len(q.buffer) - 1
+	ret := q.buffer[(q.first+i)&modBits]
+	if i < q.size/2 {
+		// Shift the first i elements one place towards last.
+		for k := i; k > 0; k-- {
+			q.buffer[(q.first+k)&modBits] = q.buffer[(q.first+k-1)&modBits]
+		}
+		q.buffer[q.first] = nil
+		q.first = q.next(q.first)
+	} else {
+		// Shift the elements after i one place towards first.
+		for k := i; k < q.size-1; k++ {
+			q.buffer[(q.first+k)&modBits] = q.buffer[(q.first+k+1)&modBits]
+		}
+		q.last = q.prev(q.last)
+		q.buffer[q.last] = nil
+	}
+	q.size--
+
+	q.compactIfNeeded()
+	return ret
+}
+
 // Clear removes all elements from the deque
 func (q *Deque) Clear() {
 	// bitwise modulus
@@ -171,6 +241,53 @@ func (q *Deque) Rotate(n int) {
 	}
 }
 
+// All returns an iterator over the index and element pairs of the
+// deque, First to Last. The deque must not be modified while iterating.
+func (q *Deque) All() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := 0; i < q.size; i++ {
+			if !yield(i, q.buffer[(q.first+i)&(len(q.buffer)-1)]) { return }
+		}
+	}
+}
+
+// Backward returns an iterator over the index and element pairs of the
+// deque, Last to First.
+func (q *Deque) Backward() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := q.size - 1; i >= 0; i-- {
+			if !yield(i, q.buffer[(q.first+i)&(len(q.buffer)-1)]) { return }
+		}
+	}
+}
+
+// Values returns an iterator over the elements of the deque, First to Last.
+func (q *Deque) Values() iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		for i := 0; i < q.size; i++ {
+			if !yield(q.buffer[(q.first+i)&(len(q.buffer)-1)]) { return }
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, encoding the deque as an array
+// of its elements First to Last.
+func (q Deque) MarshalJSON() ([]byte, error) {
+	values := make([]interface{}, 0, q.size)
+	for v := range q.Values() { values = append(values, v) }
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the contents of
+// the deque with the elements of a JSON array, First to Last.
+func (q *Deque) UnmarshalJSON(data []byte) error {
+	var values []interface{}
+	if err := json.Unmarshal(data, &values); err != nil { return err }
+	q.Clear()
+	for _, v := range values { q.PushLast(v) }
+	return nil
+}
+
 // prev returns the previous buffer position wrapping around buffer.
 func (q *Deque) prev(i int) int {
 	return (i - 1) & (len(q.buffer) - 1) // bitwise modulus
@@ -1,6 +1,10 @@
 package deque
 
-import "testing"
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
 
 func TestEmpty(t *testing.T) {
 	var q Deque
@@ -314,12 +318,12 @@ func TestInsert(t *testing.T) {
 	for _, x := range "ABCDEFG" {
 		q.PushLast(x)
 	}
-	insert(q, 4, 'x') // ABCDxEFG
+	q.Insert(4, 'x') // ABCDxEFG
 	if q.At(4) != 'x' {
 		t.Error("expected x at position 4")
 	}
 
-	insert(q, 2, 'y') // AByCDxEFG
+	q.Insert(2, 'y') // AByCDxEFG
 	if q.At(2) != 'y' {
 		t.Error("expected y at position 2")
 	}
@@ -327,12 +331,12 @@ func TestInsert(t *testing.T) {
 		t.Error("expected x at position 5")
 	}
 
-	insert(q, 0, 'b') // bAByCDxEFG
+	q.Insert(0, 'b') // bAByCDxEFG
 	if q.First() != 'b' {
 		t.Error("expected b inserted at First")
 	}
 
-	insert(q, q.Len(), 'e') // bAByCDxEFGe
+	q.Insert(q.Len(), 'e') // bAByCDxEFGe
 
 	for i, x := range "bAByCDxEFGe" {
 		if q.PopFirst() != x {
@@ -347,25 +351,25 @@ func TestRemove(t *testing.T) {
 		q.PushLast(x)
 	}
 
-	if remove(q, 4) != 'E' { // ABCDFG
+	if q.Remove(4) != 'E' { // ABCDFG
 		t.Error("expected E from position 4")
 	}
 
-	if remove(q, 2) != 'C' { // ABDFG
+	if q.Remove(2) != 'C' { // ABDFG
 		t.Error("expected C at position 2")
 	}
 	if q.Last() != 'G' {
 		t.Error("expected G at Last")
 	}
 
-	if remove(q, 0) != 'A' { // BDFG
+	if q.Remove(0) != 'A' { // BDFG
 		t.Error("expected to remove A from First")
 	}
 	if q.First() != 'B' {
 		t.Error("expected G at Last")
 	}
 
-	if remove(q, q.Len()-1) != 'G' { // BDF
+	if q.Remove(q.Len()-1) != 'G' { // BDF
 		t.Error("expected to remove G from Last")
 	}
 	if q.Last() != 'F' {
@@ -377,6 +381,53 @@ func TestRemove(t *testing.T) {
 	}
 }
 
+func TestInsertRemoveShift(t *testing.T) {
+	q := new(Deque)
+	var ref []int
+	// Grow past several resizes with inserts on both halves, then
+	// shrink back with removes, checking against a plain slice.
+	for n := 0; n < 300; n++ {
+		i := (n * 7) % (len(ref) + 1)
+		q.Insert(i, n)
+		ref = append(ref[:i], append([]int{n}, ref[i:]...)...)
+	}
+	for len(ref) > 0 {
+		i := (len(ref) * 5) % len(ref)
+		if x := q.Remove(i); x != ref[i] {
+			t.Fatalf("Remove(%d) = %v, expected %d", i, x, ref[i])
+		}
+		ref = append(ref[:i], ref[i+1:]...)
+		if q.Len() != len(ref) {
+			t.Fatalf("q.Len() = %d, expected %d", q.Len(), len(ref))
+		}
+		for j := range ref {
+			if q.At(j) != ref[j] {
+				t.Fatalf("At(%d) = %v, expected %d", j, q.At(j), ref[j])
+			}
+		}
+	}
+}
+
+func TestInsertRemoveOutOfRange(t *testing.T) {
+	q := new(Deque)
+	q.Insert(1, "X")
+	if q.Len() != 0 {
+		t.Error("Insert() out of range changed the deque")
+	}
+	q.PushLast("A")
+	q.Insert(-1, "Y")
+	q.Insert(2, "B")
+	if q.Len() != 1 {
+		t.Error("Insert() out of range changed the deque")
+	}
+	if q.Remove(-1) != nil || q.Remove(1) != nil {
+		t.Error("Remove() out of range should return nil")
+	}
+	if q.Len() != 1 {
+		t.Error("Remove() out of range changed the deque")
+	}
+}
+
 /*
 func TestFrontBackOutOfRangePanics(t *testing.T) {
 	const msg = "should panic when peeking empty queue"
@@ -449,17 +500,17 @@ func TestInsertOutOfRangePanics(t *testing.T) {
 	q := new(Deque)
 
 	assertPanics(t, "should panic when inserting out of range", func() {
-		insert(q, 1, "X")
+		q.Insert(1, "X")
 	})
 
 	q.PushLast("A")
 
 	assertPanics(t, "should panic when inserting at negative index", func() {
-		insert(q, -1, "Y")
+		q.Insert(-1, "Y")
 	})
 
 	assertPanics(t, "should panic when inserting out of range", func() {
-		insert(q, 2, "B")
+		q.Insert(2, "B")
 	})
 }
 
@@ -467,17 +518,17 @@ func TestRemoveOutOfRangePanics(t *testing.T) {
 	q := new(Deque)
 
 	assertPanics(t, "should panic when removing from empty queue", func() {
-		remove(q, 0)
+		q.Remove(0)
 	})
 
 	q.PushLast("A")
 
 	assertPanics(t, "should panic when removing at negative index", func() {
-		remove(q, -1)
+		q.Remove(-1)
 	})
 
 	assertPanics(t, "should panic when removing out of range", func() {
-		remove(q, 1)
+		q.Remove(1)
 	})
 }
 
@@ -546,7 +597,7 @@ func BenchmarkInsert(b *testing.B) {
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		insert(q, q.Len()/2, -i)
+		q.Insert(q.Len()/2, -i)
 	}
 }
 
@@ -557,64 +608,87 @@ func BenchmarkRemove(b *testing.B) {
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		remove(q, q.Len()/2)
-	}
-}
-
-// insert is used to insert an element into the middle of the queue, before the
-// element at the specified index.  insert(0,e) is the same as PushFirst(e) and
-// insert(Len(),e) is the same as PushLast(e).  Complexity is constant plus
-// linear in the lesser of the distances between i and either of the ends of
-// the queue.  Accepts only non-negative index values, and panics if index is
-// out of range.
-func insert(q *Deque, i int, elem interface{}) {
-	if i < 0 || i > q.Len() {
-		panic("deque: Insert() called with index out of range")
-	}
-	if i == 0 {
-		q.PushFirst(elem)
-		return
-	}
-	if i == q.Len() {
-		q.PushLast(elem)
-		return
-	}
-	if i <= q.Len()/2 {
-		q.Rotate(i)
-		q.PushFirst(elem)
-		q.Rotate(-i)
-	} else {
-		rots := q.Len() - i
-		q.Rotate(-rots)
-		q.PushLast(elem)
-		q.Rotate(rots)
-	}
-}
-
-// remove removes and returns an element from the middle of the queue, at the
-// specified index.  remove(0) is the same as PopFirst() and remove(Len()-1) is
-// the same as PopLast().  Complexity is constant plus linear in the lesser of
-// the distances between i and either of the ends of the queue.  Accepts only
-// non-negative index values, and panics if index is out of range.
-func remove(q *Deque, i int) interface{} {
-	if i < 0 || i >= q.Len() {
-		panic("deque: Remove() called with index out of range")
-	}
-	if i == 0 {
-		return q.PopFirst()
-	}
-	if i == q.Len()-1 {
-		return q.PopLast()
-	}
-	if i <= q.Len()/2 {
-		q.Rotate(i)
-		elem := q.PopFirst()
-		q.Rotate(-i)
-		return elem
-	}
-	rots := q.Len() - 1 - i
-	q.Rotate(-rots)
-	elem := q.PopLast()
-	q.Rotate(rots)
-	return elem
+		q.Remove(q.Len()/2)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	var q Deque
+	for i := 0; i < minSize+10; i++ {
+		q.PushLast(i)
+	}
+	for i := 0; i < 20; i++ {
+		q.PopFirst()
+		q.PushLast(minSize + 10 + i)
+	}
+	for i, x := range q.All() {
+		if x != q.At(i) {
+			t.Fatalf("All() gave %v at %d, expected %v", x, i, q.At(i))
+		}
+	}
+	next := q.Len() - 1
+	for i, x := range q.Backward() {
+		if i != next || x != q.At(i) {
+			t.Fatalf("Backward() gave %v at %d, expected %v at %d", x, i, q.At(next), next)
+		}
+		next--
+	}
+	values := slices.Collect(q.Values())
+	if len(values) != q.Len() || values[0] != 20 || values[len(values)-1] != minSize+29 {
+		t.Error("Values() collected", values)
+	}
+	n := 0
+	for range q.Values() {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Error("Values() did not stop at break")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var q Deque
+	for i := 0; i < 6; i++ {
+		q.PushLast(i)
+	}
+	q.Rotate(2)
+	b, err := json.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "[2,3,4,5,0,1]" {
+		t.Fatal("json.Marshal gave", string(b))
+	}
+	var r Deque
+	r.PushLast("stale")
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 6 || r.First() != 2.0 || r.Last() != 1.0 {
+		t.Error("json.Unmarshal gave", slices.Collect(r.Values()))
+	}
+	if err := json.Unmarshal([]byte(`{"a":1}`), &r); err == nil {
+		t.Error("expected error unmarshalling an object")
+	}
+}
+
+func TestJSONByValue(t *testing.T) {
+	var q Deque
+	q.PushLast(1)
+	q.PushLast(2)
+	b, err := json.Marshal(q)
+	if err != nil || string(b) != "[1,2]" {
+		t.Errorf("json.Marshal by value gave %s, %v", b, err)
+	}
+	wrapped := struct{ Q Deque }{q}
+	b, err = json.Marshal(wrapped)
+	if err != nil || string(b) != `{"Q":[1,2]}` {
+		t.Errorf("json.Marshal of struct field gave %s, %v", b, err)
+	}
+	b, err = json.Marshal(map[string]Deque{"q": q})
+	if err != nil || string(b) != `{"q":[1,2]}` {
+		t.Errorf("json.Marshal of map value gave %s, %v", b, err)
+	}
 }
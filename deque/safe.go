@@ -0,0 +1,162 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+
+package deque
+
+import "sync"
+
+// SafeDeque is a Deque that may be used by several goroutines at once.
+// Methods that only browse the deque take a read lock, so readers do
+// not block each other. The zero value is an empty deque ready to use.
+type SafeDeque struct {
+	mu	sync.RWMutex
+	q	Deque
+}
+
+// Len returns the number of elements in the deque
+func (s *SafeDeque) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.Len()
+}
+
+// PushLast appends an element to the Last of the deque
+func (s *SafeDeque) PushLast(dequeitem interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.PushLast(dequeitem)
+}
+
+// PushFirst adds an element to the First of the deque
+func (s *SafeDeque) PushFirst(dequeitem interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.PushFirst(dequeitem)
+}
+
+// PopFirst removes and returns the first of the deque
+func (s *SafeDeque) PopFirst() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.PopFirst()
+}
+
+// PopLast removes and returns the element from the Last of the deque
+func (s *SafeDeque) PopLast() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.PopLast()
+}
+
+// PopFirstIf removes and returns the first of the deque only if the
+// deque is not empty and f returns true for it. The check and the
+// removal happen under the same lock.
+func (s *SafeDeque) PopFirstIf(f func(interface{}) bool) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.q.Len() == 0 || !f(s.q.First()) { return nil, false }
+	return s.q.PopFirst(), true
+}
+
+// PopLastIf removes and returns the Last of the deque only if the
+// deque is not empty and f returns true for it.
+func (s *SafeDeque) PopLastIf(f func(interface{}) bool) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.q.Len() == 0 || !f(s.q.Last()) { return nil, false }
+	return s.q.PopLast(), true
+}
+
+// PushPop appends an element to the Last of the deque and removes
+// and returns the First, as one operation. On an empty deque the
+// element pushed is the one returned.
+func (s *SafeDeque) PushPop(dequeitem interface{}) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.PushLast(dequeitem)
+	return s.q.PopFirst()
+}
+
+// First returns (browse) the element at the First of the deque
+func (s *SafeDeque) First() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.First()
+}
+
+// Last returns (browse) the element at the Last of the deque
+func (s *SafeDeque) Last() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.Last()
+}
+
+// At returns (browse) the element at index i in the deque
+func (s *SafeDeque) At(i int) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.At(i)
+}
+
+// Insert inserts an element before the element at index i
+func (s *SafeDeque) Insert(i int, dequeitem interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.Insert(i, dequeitem)
+}
+
+// Remove removes and returns the element at index i
+func (s *SafeDeque) Remove(i int) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.Remove(i)
+}
+
+// Clear removes all elements from the deque
+func (s *SafeDeque) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.Clear()
+}
+
+// Rotate rotates the deque +n steps First-to-Last
+//                          -n steps Last-to-First
+func (s *SafeDeque) Rotate(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.Rotate(n)
+}
+
+// View calls f with the underlying deque under the read lock. f must
+// not modify the deque nor keep it after returning.
+func (s *SafeDeque) View(f func(q *Deque)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f(&s.q)
+}
+
+// Update calls f with the underlying deque under the write lock, so
+// any sequence of operations done by f is atomic.
+func (s *SafeDeque) Update(f func(q *Deque)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(&s.q)
+}
@@ -0,0 +1,77 @@
+package deque
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSafeConcurrent(t *testing.T) {
+	var q SafeDeque
+	var wg sync.WaitGroup
+	const producers, items = 4, 1000
+
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				q.PushLast(p*items + i)
+				q.At(0)
+			}
+		}(p)
+	}
+	seen := make([]bool, producers*items)
+	var mu sync.Mutex
+	for c := 0; c < producers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < items; {
+				x, ok := q.PopFirstIf(func(interface{}) bool { return true })
+				if !ok {
+					continue
+				}
+				mu.Lock()
+				if seen[x.(int)] {
+					t.Error("element", x, "popped twice")
+				}
+				seen[x.(int)] = true
+				mu.Unlock()
+				n++
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, ok := range seen {
+		if !ok {
+			t.Fatal("element", i, "never popped")
+		}
+	}
+	if q.Len() != 0 {
+		t.Error("q.Len() =", q.Len(), "expected 0")
+	}
+}
+
+func TestSafePopIf(t *testing.T) {
+	var q SafeDeque
+	if _, ok := q.PopFirstIf(func(interface{}) bool { return true }); ok {
+		t.Error("PopFirstIf on empty deque returned ok")
+	}
+	for i := 0; i < 5; i++ {
+		q.PushLast(i)
+	}
+	even := func(x interface{}) bool { return x.(int)%2 == 0 }
+	if x, ok := q.PopFirstIf(even); !ok || x != 0 {
+		t.Error("PopFirstIf(even) =", x, ok)
+	}
+	if _, ok := q.PopFirstIf(even); ok {
+		t.Error("PopFirstIf(even) removed odd First")
+	}
+	if x, ok := q.PopLastIf(even); !ok || x != 4 {
+		t.Error("PopLastIf(even) =", x, ok)
+	}
+	if x := q.PushPop(9); x != 1 || q.Last() != 9 || q.Len() != 3 {
+		t.Error("PushPop(9) =", x)
+	}
+}
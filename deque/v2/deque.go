@@ -0,0 +1,307 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package deque provides a type-parameterized version of the deque ring
+// buffer. It keeps the same power of 2 buffer and bitwise arithmetic as the
+// original interface{} based deque, but elements are stored with their own
+// type and reads return a second boolean value instead of a nil element.
+package deque
+
+import (
+	"encoding/json"
+	"iter"
+)
+
+// Power of 2 for bitwise modulus: x % n == x & (n - 1).
+const minSize = 64
+
+// Deque represents a single instance of the data structure holding
+// elements of type T.
+type Deque[T any] struct {
+	buffer []T
+	first  int
+	last   int
+	size   int
+}
+
+// Len returns the number of elements in the deque
+func (q *Deque[T]) Len() int {
+	return q.size
+}
+
+//------------------------------------------------------------------------
+// FIFO deque: 	add with PushLast()
+// 				remove with PopFirst()
+// LIFO deque: 	add with PushFirst()
+// 				remove with PopLast()
+// Pop from an empty deque returns the zero value of T and false
+//------------------------------------------------------------------------
+
+// PushLast appends an element to the Last of the deque
+func (q *Deque[T]) PushLast(dequeitem T) {
+	q.resizeIfNeeded()
+
+	q.buffer[q.last] = dequeitem
+	// Calculate new last position.
+	q.last = q.next(q.last)
+	q.size++
+}
+
+// PushFirst adds an element to the First of the deque
+func (q *Deque[T]) PushFirst(dequeitem T) {
+	q.resizeIfNeeded()
+
+	// Calculate new first position.
+	q.first = q.prev(q.first)
+	q.buffer[q.first] = dequeitem
+	q.size++
+}
+
+// PopFirst removes and returns the first of the deque.
+// The boolean is false if the deque was empty.
+func (q *Deque[T]) PopFirst() (T, bool) {
+	var zero T
+	if q.size <= 0 {
+		return zero, false
+	}
+	ret := q.buffer[q.first]
+	q.buffer[q.first] = zero
+	// Calculate new first position.
+	q.first = q.next(q.first)
+	q.size--
+
+	q.compactIfNeeded()
+	return ret, true
+}
+
+// PopLast removes and returns the element from the Last of the deque.
+// The boolean is false if the deque was empty.
+func (q *Deque[T]) PopLast() (T, bool) {
+	var zero T
+	if q.size <= 0 {
+		return zero, false
+	}
+
+	// Calculate new last position
+	q.last = q.prev(q.last)
+
+	// Remove value at last.
+	ret := q.buffer[q.last]
+	q.buffer[q.last] = zero
+	q.size--
+
+	q.compactIfNeeded()
+	return ret, true
+}
+
+// First returns (browse) the element at the First of the deque,
+// that would be returned by PopFirst()
+func (q *Deque[T]) First() (T, bool) {
+	if q.size <= 0 {
+		var zero T
+		return zero, false
+	}
+	return q.buffer[q.first], true
+}
+
+// Last returns the element at the Last of the deque,
+// that would be returned by PopLast()
+func (q *Deque[T]) Last() (T, bool) {
+	if q.size <= 0 {
+		var zero T
+		return zero, false
+	}
+	return q.buffer[q.prev(q.last)], true
+}
+
+// At returns (browse) the element at index i in the deque
+// without removing the element. Index i is non negative.
+// Index 0        is the first element and same as First()
+// Index Len()-1  is the last  element and same as Last()
+// The boolean is false if i is out of range.
+func (q *Deque[T]) At(i int) (T, bool) {
+	if i < 0 || i >= q.size {
+		var zero T
+		return zero, false
+	}
+	// bitwise modulus
+	return q.buffer[(q.first+i)&(len(q.buffer)-1)], true
+}
+
+// Clear removes all elements from the deque
+func (q *Deque[T]) Clear() {
+	var zero T
+	// bitwise modulus
+	modBits := len(q.buffer) - 1
+	for h, n := q.first, 0; n < q.size; h, n = (h+1)&modBits, n+1 {
+		q.buffer[h] = zero
+	}
+	q.first = 0
+	q.last = 0
+	q.size = 0
+}
+
+// Rotate rotates the deque n steps First-to-Last. If n is negative,
+// rotates -n steps Last-to-First.
+func (q *Deque[T]) Rotate(n int) {
+	if q.size <= 1 {
+		return
+	}
+	// Rotating a multiple of q.size is same as no rotation.
+	n %= q.size
+	if n == 0 {
+		return
+	}
+
+	var zero T
+	modBits := len(q.buffer) - 1
+	// If no empty space in buffer, only move first and last indexes.
+	if q.first == q.last {
+		// Calculate new first and last using bitwise modulus.
+		q.first = (q.first + n) & modBits
+		q.last = (q.last + n) & modBits
+		return
+	}
+
+	if n < 0 {
+		// Rotate Last to First.
+		for ; n < 0; n++ {
+			// Calculate new first and last using bitwise modulus.
+			q.first = (q.first - 1) & modBits
+			q.last = (q.last - 1) & modBits
+			// Put last value at first and remove value at last.
+			q.buffer[q.first] = q.buffer[q.last]
+			q.buffer[q.last] = zero
+		}
+		return
+	}
+
+	// Rotate First to Last.
+	for ; n > 0; n-- {
+		// Put first value at last and remove value at first.
+		q.buffer[q.last] = q.buffer[q.first]
+		q.buffer[q.first] = zero
+		// Calculate new first and last using bitwise modulus.
+		q.first = (q.first + 1) & modBits
+		q.last = (q.last + 1) & modBits
+	}
+}
+
+// All returns an iterator over the index and element pairs of the
+// deque, First to Last. The deque must not be modified while iterating.
+func (q *Deque[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := 0; i < q.size; i++ {
+			if !yield(i, q.buffer[(q.first+i)&(len(q.buffer)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the index and element pairs of the
+// deque, Last to First.
+func (q *Deque[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := q.size - 1; i >= 0; i-- {
+			if !yield(i, q.buffer[(q.first+i)&(len(q.buffer)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the elements of the deque, First to Last.
+func (q *Deque[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < q.size; i++ {
+			if !yield(q.buffer[(q.first+i)&(len(q.buffer)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, encoding the deque as an array
+// of its elements First to Last.
+func (q Deque[T]) MarshalJSON() ([]byte, error) {
+	values := make([]T, 0, q.size)
+	for v := range q.Values() {
+		values = append(values, v)
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the contents of
+// the deque with the elements of a JSON array, First to Last.
+func (q *Deque[T]) UnmarshalJSON(data []byte) error {
+	var values []T
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	q.Clear()
+	for _, v := range values {
+		q.PushLast(v)
+	}
+	return nil
+}
+
+// prev returns the previous buffer position wrapping around buffer.
+func (q *Deque[T]) prev(i int) int {
+	return (i - 1) & (len(q.buffer) - 1) // bitwise modulus
+}
+
+// next returns the next buffer position wrapping around buffer.
+func (q *Deque[T]) next(i int) int {
+	return (i + 1) & (len(q.buffer) - 1) // bitwise modulus
+}
+
+// resizeIfNeeded resizes up if the buffer is full.
+func (q *Deque[T]) resizeIfNeeded() {
+	if len(q.buffer) == 0 {
+		q.buffer = make([]T, minSize)
+		return
+	}
+	if q.size == len(q.buffer) {
+		q.resize()
+	}
+}
+
+// compactIfNeeded resize down if the buffer 1/4 full.
+func (q *Deque[T]) compactIfNeeded() {
+	if len(q.buffer) > minSize && (q.size<<2) == len(q.buffer) {
+		q.resize()
+	}
+}
+
+// resizes the deque to fit exactly twice its current contents
+func (q *Deque[T]) resize() {
+	newBuf := make([]T, q.size<<1)
+	if q.last > q.first {
+		copy(newBuf, q.buffer[q.first:q.last])
+	} else {
+		n := copy(newBuf, q.buffer[q.first:])
+		copy(newBuf[n:], q.buffer[:q.last])
+	}
+
+	q.first = 0
+	q.last = q.size
+	q.buffer = newBuf
+}
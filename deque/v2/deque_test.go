@@ -0,0 +1,245 @@
+package deque
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+type measure struct {
+	msgNum  int
+	isAlert bool
+}
+
+func TestEmpty(t *testing.T) {
+	var q Deque[int]
+	if q.Len() != 0 {
+		t.Error("q.Len() =", q.Len(), "expect 0")
+	}
+	if _, ok := q.First(); ok {
+		t.Error("First() on empty deque returned ok")
+	}
+	if _, ok := q.Last(); ok {
+		t.Error("Last() on empty deque returned ok")
+	}
+	if _, ok := q.PopFirst(); ok {
+		t.Error("PopFirst() on empty deque returned ok")
+	}
+	if _, ok := q.PopLast(); ok {
+		t.Error("PopLast() on empty deque returned ok")
+	}
+}
+
+func TestFrontBack(t *testing.T) {
+	var q Deque[string]
+	q.PushLast("foo")
+	q.PushLast("bar")
+	q.PushLast("baz")
+	if v, _ := q.First(); v != "foo" {
+		t.Error("wrong value at First of queue")
+	}
+	if v, _ := q.Last(); v != "baz" {
+		t.Error("wrong value at Last of queue")
+	}
+
+	if v, _ := q.PopFirst(); v != "foo" {
+		t.Error("wrong value removed from First of queue")
+	}
+	if v, _ := q.First(); v != "bar" {
+		t.Error("wrong value remaining at First of queue")
+	}
+	if v, _ := q.PopLast(); v != "baz" {
+		t.Error("wrong value removed from Last of queue")
+	}
+	if v, _ := q.Last(); v != "bar" {
+		t.Error("wrong value remaining at Last of queue")
+	}
+}
+
+func TestGrowShrink(t *testing.T) {
+	var q Deque[int]
+	size := minSize * 2
+
+	for i := 0; i < size; i++ {
+		q.PushLast(i)
+	}
+	bufLen := len(q.buffer)
+
+	for i := 0; i < size; i++ {
+		x, ok := q.PopFirst()
+		if !ok || x != i {
+			t.Error("q.PopFirst() =", x, ok, "expected", i)
+		}
+	}
+	if q.Len() != 0 {
+		t.Error("q.Len() =", q.Len(), "expected 0")
+	}
+	if len(q.buffer) == bufLen {
+		t.Error("queue buffer did not shrink")
+	}
+}
+
+func TestBufferWrap(t *testing.T) {
+	var q Deque[int]
+
+	for i := 0; i < minSize; i++ {
+		q.PushLast(i)
+	}
+	for i := 0; i < 3; i++ {
+		q.PopFirst()
+		q.PushLast(minSize + i)
+	}
+	for i := 0; i < minSize; i++ {
+		if v, _ := q.First(); v != i+3 {
+			t.Error("peek", i, "had value", v)
+		}
+		q.PopFirst()
+	}
+}
+
+func TestRotate(t *testing.T) {
+	var q Deque[int]
+	for i := 0; i < 10; i++ {
+		q.PushLast(i)
+	}
+	q.Rotate(11)
+	if v, _ := q.First(); v != 1 {
+		t.Error("rotating 11 places should have been same as one")
+	}
+	q.Rotate(-21)
+	if v, _ := q.First(); v != 0 {
+		t.Error("rotating -21 places should have been same as one -1")
+	}
+	for i := 0; i < q.Len(); i++ {
+		q.Rotate(1)
+		if v, _ := q.Last(); v != i {
+			t.Fatal("wrong value during rotation")
+		}
+	}
+}
+
+func TestAt(t *testing.T) {
+	var q Deque[measure]
+
+	for i := 0; i < 1000; i++ {
+		q.PushLast(measure{msgNum: i, isAlert: i%2 == 0})
+	}
+	for j := 0; j < q.Len(); j++ {
+		if v, ok := q.At(j); !ok || v.msgNum != j {
+			t.Errorf("index %d doesn't contain %d", j, j)
+		}
+	}
+	if _, ok := q.At(-1); ok {
+		t.Error("At(-1) returned ok")
+	}
+	if _, ok := q.At(q.Len()); ok {
+		t.Error("At(Len()) returned ok")
+	}
+}
+
+func TestClear(t *testing.T) {
+	var q Deque[*measure]
+
+	for i := 0; i < minSize; i++ {
+		q.PushLast(&measure{msgNum: i})
+	}
+	cap := len(q.buffer)
+	q.Clear()
+	if q.Len() != 0 {
+		t.Error("empty queue length not 0 after clear")
+	}
+	if len(q.buffer) != cap {
+		t.Error("queue capacity changed after clear")
+	}
+	// Check that there are no remaining references after Clear()
+	for i := 0; i < len(q.buffer); i++ {
+		if q.buffer[i] != nil {
+			t.Error("queue has non-nil deleted elements after Clear()")
+			break
+		}
+	}
+}
+
+func BenchmarkSerial(b *testing.B) {
+	var q Deque[int]
+	for i := 0; i < b.N; i++ {
+		q.PushLast(i)
+	}
+	for i := 0; i < b.N; i++ {
+		q.PopFirst()
+	}
+}
+
+func BenchmarkSerialReverse(b *testing.B) {
+	var q Deque[int]
+	for i := 0; i < b.N; i++ {
+		q.PushFirst(i)
+	}
+	for i := 0; i < b.N; i++ {
+		q.PopLast()
+	}
+}
+
+func TestIterators(t *testing.T) {
+	var q Deque[int]
+	for i := 0; i < 10; i++ {
+		q.PushFirst(9 - i)
+	}
+	if got := slices.Collect(q.Values()); !slices.Equal(got, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Error("Values() collected", got)
+	}
+	var back []int
+	for i, v := range q.Backward() {
+		if i != v {
+			t.Error("Backward() gave", v, "at", i)
+		}
+		if back = append(back, v); len(back) == 4 {
+			break
+		}
+	}
+	if !slices.Equal(back, []int{9, 8, 7, 6}) {
+		t.Error("Backward() visited", back)
+	}
+	for i, v := range q.All() {
+		if i != v {
+			t.Error("All() gave", v, "at", i)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var q Deque[int]
+	for i := 0; i < 6; i++ {
+		q.PushLast(i)
+	}
+	q.Rotate(2)
+	b, err := json.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "[2,3,4,5,0,1]" {
+		t.Fatal("json.Marshal gave", string(b))
+	}
+	var r Deque[int]
+	r.PushLast(99)
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Collect(r.Values()); !slices.Equal(got, []int{2, 3, 4, 5, 0, 1}) {
+		t.Error("json.Unmarshal gave", got)
+	}
+}
+
+func TestJSONByValue(t *testing.T) {
+	var q Deque[int]
+	q.PushLast(1)
+	q.PushLast(2)
+	b, err := json.Marshal(q)
+	if err != nil || string(b) != "[1,2]" {
+		t.Errorf("json.Marshal by value gave %s, %v", b, err)
+	}
+	b, err = json.Marshal(struct{ Q Deque[int] }{q})
+	if err != nil || string(b) != `{"Q":[1,2]}` {
+		t.Errorf("json.Marshal of struct field gave %s, %v", b, err)
+	}
+}
@@ -1,5 +1,10 @@
 package duplexqueue
 
+import (
+	"encoding/json"
+	"iter"
+)
+
 // minCapacity is the smallest capacity that duplexqueue may have.
 // Must be power of 2 for bitwise modulus: x % n == x & (n - 1).
 const minCapacity = 4
@@ -34,7 +39,7 @@ func (q *Duplexqueue) Init(qty int, elem interface{}) {
 	for i := 0; i < qty; i++ {
 		q.Buf[i] = elem
 	}
-	q.Tail   = qty - 1
+	q.Tail   = qty & (qsz - 1)
 	q.Count  = qty 
 }
 
@@ -64,13 +69,13 @@ func (q *Duplexqueue) PushFront(elem interface{}) {
 // PushPop pops back (returns that value) and pushes at front
 func (q *Duplexqueue) PushPop(elem interface{}) interface{} {
 	if q.Count <= 0 {
-		panic("duplexqueue: PopBack() called on empty queue")
+		panic("duplexqueue: PushPop() called on empty queue")
 	}
 
-	// Remove value at Tail.
+	// Calculate new Tail position and remove value at Tail.
+	q.Tail = q.prev(q.Tail)
 	ret := q.Buf[q.Tail]
 	q.Buf[q.Tail] = nil
-	q.Tail = q.prev(q.Tail)
 	// Calculate new Head position.
 	q.Head = q.prev(q.Head)
 	q.Buf[q.Head] = elem
@@ -165,6 +170,80 @@ func (q *Duplexqueue) Index(i int) interface{} {
 }
 
 
+// Insert inserts an element into the middle of the queue, before the element
+// at index i, so that At(i) returns it afterwards.  Insert(0,e) is the same as
+// PushFront(e) and Insert(Len(),e) is the same as PushBack(e).  The elements
+// between i and the nearest end of the queue are shifted one place in the
+// buffer, so complexity is constant plus linear in the lesser of the distances
+// between i and either of the ends of the queue.  Accepts only non-negative
+// index values, and panics if index is out of range.
+func (q *Duplexqueue) Insert(i int, elem interface{}) {
+	if i < 0 || i > q.Count {
+		panic("duplexqueue: Insert() called with index out of range")
+	}
+	if i == 0 {
+		q.PushFront(elem)
+		return
+	}
+	if i == q.Count {
+		q.PushBack(elem)
+		return
+	}
+	q.growIfFull()
+
+	modBits := len(q.Buf) - 1
+	if i < q.Count/2 {
+		// Shift the first i elements one place towards Head.
+		q.Head = q.prev(q.Head)
+		for k := 0; k < i; k++ {
+			q.Buf[(q.Head+k)&modBits] = q.Buf[(q.Head+k+1)&modBits]
+		}
+	} else {
+		// Shift the elements from i one place towards Tail.
+		for k := q.Count; k > i; k-- {
+			q.Buf[(q.Head+k)&modBits] = q.Buf[(q.Head+k-1)&modBits]
+		}
+		q.Tail = q.next(q.Tail)
+	}
+	q.Buf[(q.Head+i)&modBits] = elem
+	q.Count++
+}
+
+// Remove removes and returns an element from the middle of the queue, at the
+// specified index.  Remove(0) is the same as PopFront() and Remove(Len()-1) is
+// the same as PopBack().  The elements between i and the nearest end of the
+// queue are shifted one place in the buffer, so complexity is constant plus
+// linear in the lesser of the distances between i and either of the ends of
+// the queue.  Accepts only non-negative index values, and panics if index is
+// out of range.
+func (q *Duplexqueue) Remove(i int) interface{} {
+	if i < 0 || i >= q.Count {
+		panic("duplexqueue: Remove() called with index out of range")
+	}
+
+	modBits := len(q.Buf) - 1
+	ret := q.Buf[(q.Head+i)&modBits]
+	if i < q.Count/2 {
+		// Shift the first i elements one place towards Tail.
+		for k := i; k > 0; k-- {
+			q.Buf[(q.Head+k)&modBits] = q.Buf[(q.Head+k-1)&modBits]
+		}
+		q.Buf[q.Head] = nil
+		q.Head = q.next(q.Head)
+	} else {
+		// Shift the elements after i one place towards Head.
+		for k := i; k < q.Count-1; k++ {
+			q.Buf[(q.Head+k)&modBits] = q.Buf[(q.Head+k+1)&modBits]
+		}
+		q.Tail = q.prev(q.Tail)
+		q.Buf[q.Tail] = nil
+	}
+	q.Count--
+
+	q.shrinkIfExcess()
+	return ret
+}
+
 // Clear removes all elements from the queue, but retains the current capacity.
 // This is useful when repeatedly reusing the queue at high frequency to avoid
 // GC during reuse.  The queue will not be resized smaller as long as items are
@@ -227,6 +306,67 @@ func (q *Duplexqueue) Rotate(n int) {
 	}
 }
 
+// All returns an iterator over the index and element pairs of the queue, front
+// to back.  The queue must not be modified while iterating.
+func (q *Duplexqueue) All() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := 0; i < q.Count; i++ {
+			if !yield(i, q.Buf[(q.Head+i)&(len(q.Buf)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the index and element pairs of the queue,
+// back to front.
+func (q *Duplexqueue) Backward() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := q.Count - 1; i >= 0; i-- {
+			if !yield(i, q.Buf[(q.Head+i)&(len(q.Buf)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the elements of the queue, front to back.
+func (q *Duplexqueue) Values() iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		for i := 0; i < q.Count; i++ {
+			if !yield(q.Buf[(q.Head+i)&(len(q.Buf)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, encoding the queue as an array of its
+// elements from front to back.  The buffer layout, with its empty slots and
+// Head and Tail positions, is not part of the encoding.
+func (q Duplexqueue) MarshalJSON() ([]byte, error) {
+	values := make([]interface{}, 0, q.Count)
+	for v := range q.Values() {
+		values = append(values, v)
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the contents of the
+// queue with the elements of a JSON array, from front to back.
+func (q *Duplexqueue) UnmarshalJSON(data []byte) error {
+	var values []interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	var restored Duplexqueue
+	for _, v := range values {
+		restored.PushBack(v)
+	}
+	*q = restored
+	return nil
+}
+
 // prev returns the previous buffer position wrapping around buffer.
 func (q *Duplexqueue) prev(i int) int {
 	return (i - 1) & (len(q.Buf) - 1) // bitwise modulus
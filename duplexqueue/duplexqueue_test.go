@@ -1,6 +1,9 @@
 package duplexqueue
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+)
 
 func TestEmpty(t *testing.T) {
 	var q Duplexqueue
@@ -314,12 +317,12 @@ func TestInsert(t *testing.T) {
 	for _, x := range "ABCDEFG" {
 		q.PushBack(x)
 	}
-	insert(q, 4, 'x') // ABCDxEFG
+	q.Insert(4, 'x') // ABCDxEFG
 	if q.At(4) != 'x' {
 		t.Error("expected x at position 4")
 	}
 
-	insert(q, 2, 'y') // AByCDxEFG
+	q.Insert(2, 'y') // AByCDxEFG
 	if q.At(2) != 'y' {
 		t.Error("expected y at position 2")
 	}
@@ -327,12 +330,12 @@ func TestInsert(t *testing.T) {
 		t.Error("expected x at position 5")
 	}
 
-	insert(q, 0, 'b') // bAByCDxEFG
+	q.Insert(0, 'b') // bAByCDxEFG
 	if q.Front() != 'b' {
 		t.Error("expected b inserted at front")
 	}
 
-	insert(q, q.Len(), 'e') // bAByCDxEFGe
+	q.Insert(q.Len(), 'e') // bAByCDxEFGe
 
 	for i, x := range "bAByCDxEFGe" {
 		if q.PopFront() != x {
@@ -347,25 +350,25 @@ func TestRemove(t *testing.T) {
 		q.PushBack(x)
 	}
 
-	if remove(q, 4) != 'E' { // ABCDFG
+	if q.Remove(4) != 'E' { // ABCDFG
 		t.Error("expected E from position 4")
 	}
 
-	if remove(q, 2) != 'C' { // ABDFG
+	if q.Remove(2) != 'C' { // ABDFG
 		t.Error("expected C at position 2")
 	}
 	if q.Back() != 'G' {
 		t.Error("expected G at back")
 	}
 
-	if remove(q, 0) != 'A' { // BDFG
+	if q.Remove(0) != 'A' { // BDFG
 		t.Error("expected to remove A from front")
 	}
 	if q.Front() != 'B' {
 		t.Error("expected G at back")
 	}
 
-	if remove(q, q.Len()-1) != 'G' { // BDF
+	if q.Remove(q.Len()-1) != 'G' { // BDF
 		t.Error("expected to remove G from back")
 	}
 	if q.Back() != 'F' {
@@ -377,6 +380,50 @@ func TestRemove(t *testing.T) {
 	}
 }
 
+func TestInsertRemoveShift(t *testing.T) {
+	q := new(Duplexqueue)
+	var ref []int
+	// Grow past several resizes with inserts on both halves, then
+	// shrink back with removes, checking against a plain slice.
+	for n := 0; n < 300; n++ {
+		i := (n * 7) % (len(ref) + 1)
+		q.Insert(i, n)
+		ref = append(ref[:i], append([]int{n}, ref[i:]...)...)
+	}
+	for len(ref) > 0 {
+		i := (len(ref) * 5) % len(ref)
+		if x := q.Remove(i); x != ref[i] {
+			t.Fatalf("Remove(%d) = %v, expected %d", i, x, ref[i])
+		}
+		ref = append(ref[:i], ref[i+1:]...)
+		if q.Len() != len(ref) {
+			t.Fatalf("q.Len() = %d, expected %d", q.Len(), len(ref))
+		}
+		for j := range ref {
+			if q.At(j) != ref[j] {
+				t.Fatalf("At(%d) = %v, expected %d", j, q.At(j), ref[j])
+			}
+		}
+	}
+}
+
+func TestInitPushPop(t *testing.T) {
+	var q Duplexqueue
+	q.Init(4, 0)
+	for i := 1; i <= 10; i++ {
+		if x := q.PushPop(i); x != 0 && x != i-4 {
+			t.Fatalf("PushPop(%d) = %v, expected %d", i, x, i-4)
+		}
+		if q.Len() != 4 || q.Front() != i {
+			t.Fatalf("after PushPop(%d) Len() = %d Front() = %v", i, q.Len(), q.Front())
+		}
+	}
+	q.PushBack(11)
+	if q.Back() != 11 || q.At(3) != 7 {
+		t.Error("PushBack after PushPop misplaced", q.Back(), q.At(3))
+	}
+}
+
 func TestFrontBackOutOfRangePanics(t *testing.T) {
 	const msg = "should panic when peeking empty queue"
 	var q Duplexqueue
@@ -448,17 +495,17 @@ func TestInsertOutOfRangePanics(t *testing.T) {
 	q := new(Duplexqueue)
 
 	assertPanics(t, "should panic when inserting out of range", func() {
-		insert(q, 1, "X")
+		q.Insert(1, "X")
 	})
 
 	q.PushBack("A")
 
 	assertPanics(t, "should panic when inserting at negative index", func() {
-		insert(q, -1, "Y")
+		q.Insert(-1, "Y")
 	})
 
 	assertPanics(t, "should panic when inserting out of range", func() {
-		insert(q, 2, "B")
+		q.Insert(2, "B")
 	})
 }
 
@@ -466,17 +513,17 @@ func TestRemoveOutOfRangePanics(t *testing.T) {
 	q := new(Duplexqueue)
 
 	assertPanics(t, "should panic when removing from empty queue", func() {
-		remove(q, 0)
+		q.Remove(0)
 	})
 
 	q.PushBack("A")
 
 	assertPanics(t, "should panic when removing at negative index", func() {
-		remove(q, -1)
+		q.Remove(-1)
 	})
 
 	assertPanics(t, "should panic when removing out of range", func() {
-		remove(q, 1)
+		q.Remove(1)
 	})
 }
 
@@ -543,7 +590,7 @@ func BenchmarkInsert(b *testing.B) {
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		insert(q, q.Len()/2, -i)
+		q.Insert(q.Len()/2, -i)
 	}
 }
 
@@ -554,64 +601,79 @@ func BenchmarkRemove(b *testing.B) {
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		remove(q, q.Len()/2)
-	}
-}
-
-// insert is used to insert an element into the middle of the queue, before the
-// element at the specified index.  insert(0,e) is the same as PushFront(e) and
-// insert(Len(),e) is the same as PushBack(e).  Complexity is constant plus
-// linear in the lesser of the distances between i and either of the ends of
-// the queue.  Accepts only non-negative index values, and panics if index is
-// out of range.
-func insert(q *Duplexqueue, i int, elem interface{}) {
-	if i < 0 || i > q.Len() {
-		panic("duplexqueue: Insert() called with index out of range")
-	}
-	if i == 0 {
-		q.PushFront(elem)
-		return
-	}
-	if i == q.Len() {
-		q.PushBack(elem)
-		return
-	}
-	if i <= q.Len()/2 {
-		q.Rotate(i)
-		q.PushFront(elem)
-		q.Rotate(-i)
-	} else {
-		rots := q.Len() - i
-		q.Rotate(-rots)
-		q.PushBack(elem)
-		q.Rotate(rots)
-	}
-}
-
-// remove removes and returns an element from the middle of the queue, at the
-// specified index.  remove(0) is the same as PopFront() and remove(Len()-1) is
-// the same as PopBack().  Complexity is constant plus linear in the lesser of
-// the distances between i and either of the ends of the queue.  Accepts only
-// non-negative index values, and panics if index is out of range.
-func remove(q *Duplexqueue, i int) interface{} {
-	if i < 0 || i >= q.Len() {
-		panic("duplexqueue: Remove() called with index out of range")
-	}
-	if i == 0 {
-		return q.PopFront()
-	}
-	if i == q.Len()-1 {
-		return q.PopBack()
-	}
-	if i <= q.Len()/2 {
-		q.Rotate(i)
-		elem := q.PopFront()
-		q.Rotate(-i)
-		return elem
-	}
-	rots := q.Len() - 1 - i
-	q.Rotate(-rots)
-	elem := q.PopBack()
-	q.Rotate(rots)
-	return elem
+		q.Remove(q.Len()/2)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	var q Duplexqueue
+	for i := 0; i < 10; i++ {
+		q.PushBack(i)
+	}
+	q.Rotate(3)
+	for i, x := range q.All() {
+		if x != q.At(i) {
+			t.Fatalf("All() gave %v at %d, expected %v", x, i, q.At(i))
+		}
+	}
+	var back []interface{}
+	for _, x := range q.Backward() {
+		back = append(back, x)
+	}
+	if len(back) != 10 || back[0] != 2 || back[9] != 3 {
+		t.Error("Backward() visited", back)
+	}
+	var values []interface{}
+	for x := range q.Values() {
+		if values = append(values, x); len(values) == 2 {
+			break
+		}
+	}
+	if len(values) != 2 || values[0] != 3 || values[1] != 4 {
+		t.Error("Values() visited", values)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var q Duplexqueue
+	for i := 0; i < 6; i++ {
+		q.PushBack(i)
+	}
+	q.Rotate(2)
+	b, err := json.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "[2,3,4,5,0,1]" {
+		t.Fatal("json.Marshal gave", string(b))
+	}
+	var r Duplexqueue
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 6 || r.Front() != 2.0 || r.Back() != 1.0 {
+		t.Error("json.Unmarshal gave", r.Slice())
+	}
+	r.PushFront("x")
+	if r.Front() != "x" || r.Len() != 7 {
+		t.Error("queue not usable after json.Unmarshal")
+	}
+}
+
+func TestJSONByValue(t *testing.T) {
+	var q Duplexqueue
+	q.PushBack(1)
+	q.PushBack(2)
+	b, err := json.Marshal(q)
+	if err != nil || string(b) != "[1,2]" {
+		t.Errorf("json.Marshal by value gave %s, %v", b, err)
+	}
+	b, err = json.Marshal(struct{ Q Duplexqueue }{q})
+	if err != nil || string(b) != `{"Q":[1,2]}` {
+		t.Errorf("json.Marshal of struct field gave %s, %v", b, err)
+	}
+	b, err = json.Marshal(map[string]Duplexqueue{"q": q})
+	if err != nil || string(b) != `{"q":[1,2]}` {
+		t.Errorf("json.Marshal of map value gave %s, %v", b, err)
+	}
 }
@@ -0,0 +1,180 @@
+package duplexqueue
+
+import "sync"
+
+// SafeDuplexqueue is a Duplexqueue that may be used by several goroutines at
+// once.  Methods that only read the queue (Len, Front, Back, At, Index, Do,
+// DoIndex, DoFor) take a read lock, so readers do not block each other.  The
+// methods panic in the same cases as the Duplexqueue methods they wrap, and
+// the lock is released when they do.  The zero value is an empty queue ready
+// to use.
+type SafeDuplexqueue struct {
+	mu sync.RWMutex
+	q  Duplexqueue
+}
+
+// Len returns the number of elements currently stored in the queue.
+func (s *SafeDuplexqueue) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.Len()
+}
+
+// Init discards the contents of the queue and fills it with qty elements.
+func (s *SafeDuplexqueue) Init(qty int, elem interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.Init(qty, elem)
+}
+
+// PushBack appends an element to the back of the queue.
+func (s *SafeDuplexqueue) PushBack(elem interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.PushBack(elem)
+}
+
+// PushFront prepends an element to the front of the queue.
+func (s *SafeDuplexqueue) PushFront(elem interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.PushFront(elem)
+}
+
+// PushPop pops back (returns that value) and pushes at front, as one
+// operation.
+func (s *SafeDuplexqueue) PushPop(elem interface{}) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.PushPop(elem)
+}
+
+// PopFront removes and returns the element from the front of the queue.
+func (s *SafeDuplexqueue) PopFront() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.PopFront()
+}
+
+// PopBack removes and returns the element from the back of the queue.
+func (s *SafeDuplexqueue) PopBack() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.PopBack()
+}
+
+// PopFrontIf removes and returns the element from the front of the queue only
+// if the queue is not empty and f returns true for that element.  The check
+// and the removal happen under the same lock, so it never panics.
+func (s *SafeDuplexqueue) PopFrontIf(f func(interface{}) bool) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.q.Count == 0 || !f(s.q.Front()) {
+		return nil, false
+	}
+	return s.q.PopFront(), true
+}
+
+// PopBackIf removes and returns the element from the back of the queue only
+// if the queue is not empty and f returns true for that element.
+func (s *SafeDuplexqueue) PopBackIf(f func(interface{}) bool) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.q.Count == 0 || !f(s.q.Back()) {
+		return nil, false
+	}
+	return s.q.PopBack(), true
+}
+
+// Front returns the element at the front of the queue.
+func (s *SafeDuplexqueue) Front() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.Front()
+}
+
+// Back returns the element at the back of the queue.
+func (s *SafeDuplexqueue) Back() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.Back()
+}
+
+// At returns the element at index i in the queue.
+func (s *SafeDuplexqueue) At(i int) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.At(i)
+}
+
+// Index returns the element at index i wrapping around the queue.
+func (s *SafeDuplexqueue) Index(i int) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.q.Index(i)
+}
+
+// Insert inserts an element before the element at index i.
+func (s *SafeDuplexqueue) Insert(i int, elem interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.Insert(i, elem)
+}
+
+// Remove removes and returns the element at index i.
+func (s *SafeDuplexqueue) Remove(i int) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.Remove(i)
+}
+
+// Clear removes all elements from the queue, but retains the current capacity.
+func (s *SafeDuplexqueue) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.Clear()
+}
+
+// Rotate rotates the duplexqueue n steps front-to-back.
+func (s *SafeDuplexqueue) Rotate(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.q.Rotate(n)
+}
+
+// Do calls f for each element under the read lock.  f must not use s.
+func (s *SafeDuplexqueue) Do(f func(interface{})) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.q.Do(f)
+}
+
+// DoIndex calls f for each element starting at index idx under the read lock.
+func (s *SafeDuplexqueue) DoIndex(idx int, f func(interface{})) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.q.DoIndex(idx, f)
+}
+
+// DoFor calls f for cnt elements starting at index idx under the read lock.
+func (s *SafeDuplexqueue) DoFor(idx int, cnt int, f func(interface{})) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.q.DoFor(idx, cnt, f)
+}
+
+// View calls f with the underlying queue under the read lock.  f must not
+// modify the queue nor keep it after returning.
+func (s *SafeDuplexqueue) View(f func(q *Duplexqueue)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f(&s.q)
+}
+
+// Update calls f with the underlying queue under the write lock, so any
+// sequence of operations done by f is atomic.
+func (s *SafeDuplexqueue) Update(f func(q *Duplexqueue)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(&s.q)
+}
@@ -0,0 +1,75 @@
+package duplexqueue
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSafeConcurrent(t *testing.T) {
+	var q SafeDuplexqueue
+	var wg sync.WaitGroup
+	const producers, items = 4, 1000
+
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				q.PushBack(p*items + i)
+			}
+		}(p)
+	}
+	var mu sync.Mutex
+	seen := make([]bool, producers*items)
+	for c := 0; c < producers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < items; {
+				x, ok := q.PopFrontIf(func(interface{}) bool { return true })
+				if !ok {
+					q.Do(func(interface{}) {})
+					continue
+				}
+				mu.Lock()
+				if seen[x.(int)] {
+					t.Error("element", x, "popped twice")
+				}
+				seen[x.(int)] = true
+				mu.Unlock()
+				n++
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, ok := range seen {
+		if !ok {
+			t.Fatal("element", i, "never popped")
+		}
+	}
+}
+
+func TestSafePushPop(t *testing.T) {
+	var q SafeDuplexqueue
+	q.Init(8, -1)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				q.PushPop(i)
+				q.Index(-1)
+			}
+		}()
+	}
+	wg.Wait()
+	if q.Len() != 8 {
+		t.Error("PushPop changed length to", q.Len())
+	}
+	assertPanics(t, "should panic when removing empty queue", func() {
+		var e SafeDuplexqueue
+		e.PopFront()
+	})
+}
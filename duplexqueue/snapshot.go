@@ -0,0 +1,173 @@
+package duplexqueue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Snapshot layout: magic "DPXQ", version byte, element count (uvarint), then
+// for each element from front to back its encoded length (uvarint) and bytes.
+const (
+	snapshotMagic   = "DPXQ"
+	snapshotVersion = 1
+)
+
+// ErrSnapshotFormat is returned when restoring data that is not a duplexqueue
+// snapshot, or is cut short.  UnmarshalBinary also returns it for data that
+// goes on after the snapshot.
+var ErrSnapshotFormat = errors.New("duplexqueue: invalid snapshot")
+
+// ErrSnapshotVersion is returned when restoring a snapshot written by a newer
+// version of this package.
+var ErrSnapshotVersion = errors.New("duplexqueue: unsupported snapshot version")
+
+// Codec converts the elements of a queue to and from bytes for Snapshot and
+// Restore.
+type Codec interface {
+	Encode(elem interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// GobCodec encodes each element with encoding/gob, so that it is decoded with
+// its original type.  Types other than the basic ones must be registered with
+// gob.Register.  Every element is a gob stream of its own and so repeats its
+// type's name, and for a struct its whole type descriptor: an int takes 11
+// bytes.  Queues of many small elements snapshot far more compactly with a
+// Codec written for their concrete type.
+type GobCodec struct{}
+
+func (GobCodec) Encode(elem interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(&elem); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte) (interface{}, error) {
+	var elem interface{}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&elem)
+	return elem, err
+}
+
+// DefaultCodec is the codec used by MarshalBinary and UnmarshalBinary.
+var DefaultCodec Codec = GobCodec{}
+
+// Snapshot writes the elements of the queue, from front to back, to w.  Only
+// the elements are written, not the positions of Head and Tail, so the queue
+// restored from a snapshot does not depend on the layout of the buffer.
+func (q *Duplexqueue) Snapshot(w io.Writer, codec Codec) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(snapshotMagic)
+	bw.WriteByte(snapshotVersion)
+	var n [binary.MaxVarintLen64]byte
+	bw.Write(n[:binary.PutUvarint(n[:], uint64(q.Count))])
+	for i := 0; i < q.Count; i++ {
+		data, err := codec.Encode(q.Buf[(q.Head+i)&(len(q.Buf)-1)])
+		if err != nil {
+			return fmt.Errorf("duplexqueue: encoding element %d: %w", i, err)
+		}
+		bw.Write(n[:binary.PutUvarint(n[:], uint64(len(data)))])
+		bw.Write(data)
+	}
+	return bw.Flush()
+}
+
+// Restore replaces the contents of the queue with the elements of a snapshot
+// read from r.  If the snapshot cannot be read the queue is unchanged.
+func (q *Duplexqueue) Restore(r io.Reader, codec Codec) error {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &byteReader{r: r}
+	}
+	var header [len(snapshotMagic) + 1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return snapshotError(err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrSnapshotFormat
+	}
+	if header[len(snapshotMagic)] != snapshotVersion {
+		return ErrSnapshotVersion
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return snapshotError(err)
+	}
+
+	var restored Duplexqueue
+	var data bytes.Buffer
+	for i := uint64(0); i < count; i++ {
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return snapshotError(err)
+		}
+		if size > math.MaxInt64 {
+			return ErrSnapshotFormat
+		}
+		// CopyN grows data as bytes arrive, so a corrupt size runs into
+		// the end of the snapshot instead of allocating it up front.
+		data.Reset()
+		if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+			return snapshotError(err)
+		}
+		elem, err := codec.Decode(data.Bytes())
+		if err != nil {
+			return fmt.Errorf("duplexqueue: decoding element %d: %w", i, err)
+		}
+		restored.PushBack(elem)
+	}
+	*q = restored
+	return nil
+}
+
+// byteReader reads one byte at a time from r, so that Restore does not
+// consume anything past the end of the snapshot.
+type byteReader struct {
+	r io.Reader
+	b [1]byte
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(br.r, br.b[:])
+	return br.b[0], err
+}
+
+// snapshotError reports a snapshot that ends early as ErrSnapshotFormat.
+func snapshotError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrSnapshotFormat
+	}
+	return err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler with a snapshot using
+// DefaultCodec.
+func (q *Duplexqueue) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	if err := q.Snapshot(&b, DefaultCodec); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, restoring a snapshot
+// using DefaultCodec.  The snapshot must take up all of data.
+func (q *Duplexqueue) UnmarshalBinary(data []byte) error {
+	var restored Duplexqueue
+	r := bytes.NewReader(data)
+	if err := restored.Restore(r, DefaultCodec); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return ErrSnapshotFormat
+	}
+	*q = restored
+	return nil
+}
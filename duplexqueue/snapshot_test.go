@@ -0,0 +1,141 @@
+package duplexqueue
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+type measure struct {
+	MsgNum  int
+	IsAlert bool
+}
+
+func init() {
+	gob.Register(measure{})
+}
+
+// stringCodec stores string elements as their bytes.
+type stringCodec struct{}
+
+func (stringCodec) Encode(elem interface{}) ([]byte, error) { return []byte(elem.(string)), nil }
+
+func (stringCodec) Decode(data []byte) (interface{}, error) { return string(data), nil }
+
+func TestMarshalBinary(t *testing.T) {
+	var q Duplexqueue
+	for i := 0; i < 20; i++ {
+		q.PushBack(measure{MsgNum: i, IsAlert: i%3 == 0})
+	}
+	// Move Head and Tail away from the start of the buffer.
+	for i := 0; i < 15; i++ {
+		q.PopFront()
+	}
+	q.PushBack("mixed")
+	q.PushBack(42)
+
+	data, err := q.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Duplexqueue
+	r.PushBack("discarded")
+	if err := r.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != q.Len() || r.Head != 0 {
+		t.Fatal("restored queue has length", r.Len(), "and head", r.Head)
+	}
+	for i := 0; i < q.Len(); i++ {
+		if r.At(i) != q.At(i) {
+			t.Errorf("At(%d) restored %v, expected %v", i, r.At(i), q.At(i))
+		}
+	}
+	r.PushFront(measure{MsgNum: -1})
+	if r.Front().(measure).MsgNum != -1 {
+		t.Error("restored queue not usable")
+	}
+}
+
+func TestSnapshotCodec(t *testing.T) {
+	var q Duplexqueue
+	for i := 0; i < 100; i++ {
+		q.PushBack(strconv.Itoa(i))
+	}
+	var b bytes.Buffer
+	if err := q.Snapshot(&b, stringCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	// Header, count and one length byte plus the digits per element.
+	if want := 5 + 1 + 100 + 190; b.Len() != want {
+		t.Error("snapshot has", b.Len(), "bytes, expected", want)
+	}
+	var r Duplexqueue
+	if err := r.Restore(&b, stringCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 100 || r.Back() != "99" || len(r.Buf) != 128 {
+		t.Error("restored", r.Len(), "elements with back", r.Back(), "in buffer of", len(r.Buf))
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	var q Duplexqueue
+	q.PushBack("a")
+	q.PushBack("b")
+	data, _ := q.MarshalBinary()
+
+	var r Duplexqueue
+	r.PushBack("kept")
+	if err := r.UnmarshalBinary([]byte("JSON{}")); err != ErrSnapshotFormat {
+		t.Error("bad magic returned", err)
+	}
+	bad := append([]byte(nil), data...)
+	bad[4] = snapshotVersion + 1
+	if err := r.UnmarshalBinary(bad); err != ErrSnapshotVersion {
+		t.Error("bad version returned", err)
+	}
+	if err := r.UnmarshalBinary(data[:len(data)-3]); !errors.Is(err, ErrSnapshotFormat) {
+		t.Error("short snapshot returned", err)
+	}
+	huge := []byte("DPXQ\x01\x01\xff\xff\xff\xff\xff\xff\xff\xff\x7f")
+	if err := r.UnmarshalBinary(huge); err != ErrSnapshotFormat {
+		t.Error("snapshot with huge element size returned", err)
+	}
+	huge[len(huge)-1] = 0x01
+	if err := r.UnmarshalBinary(huge); err != ErrSnapshotFormat {
+		t.Error("snapshot with element size past its end returned", err)
+	}
+	if err := r.UnmarshalBinary(append(data[:len(data):len(data)], 0)); err != ErrSnapshotFormat {
+		t.Error("snapshot with trailing bytes returned", err)
+	}
+	if r.Len() != 1 || r.Front() != "kept" {
+		t.Error("failed restore changed the queue")
+	}
+}
+
+// TestRestoreStream checks that Restore from a reader that is not an
+// io.ByteReader leaves the bytes after the snapshot unread.
+func TestRestoreStream(t *testing.T) {
+	var q Duplexqueue
+	q.PushBack("a")
+	q.PushBack("b")
+	var b bytes.Buffer
+	q.Snapshot(&b, stringCodec{})
+	b.WriteString("trailer")
+
+	var r Duplexqueue
+	src := struct{ io.Reader }{&b}
+	if err := r.Restore(src, stringCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 2 || r.Back() != "b" {
+		t.Error("restored", r.Slice())
+	}
+	if rest, _ := io.ReadAll(src); string(rest) != "trailer" {
+		t.Errorf("bytes after the snapshot were %q", rest)
+	}
+}
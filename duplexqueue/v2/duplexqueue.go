@@ -0,0 +1,385 @@
+// Package duplexqueue provides a type-parameterized version of the
+// duplexqueue ring buffer. Reading or removing from an empty queue returns
+// the zero value of T and false instead of panicking.
+package duplexqueue
+
+import (
+	"encoding/json"
+	"iter"
+)
+
+// minCapacity is the smallest capacity that duplexqueue may have.
+// Must be power of 2 for bitwise modulus: x % n == x & (n - 1).
+const minCapacity = 4
+
+// Duplexqueue represents a single instance of the duplexqueue data structure
+// holding elements of type T.
+type Duplexqueue[T any] struct {
+	Buf   []T `json:"buffer"`
+	Head  int `json:"qhead"`
+	Tail  int `json:"qtail"`
+	Count int `json:"qcount"`
+}
+
+// Len returns the number of elements currently stored in the queue.
+func (q *Duplexqueue[T]) Len() int {
+	return q.Count
+}
+
+// Init discards the contents of the queue and fills it with qty copies of
+// elem. The capacity is the smallest power of 2 that holds qty elements.
+func (q *Duplexqueue[T]) Init(qty int, elem T) {
+	qsz := minCapacity
+	for qsz < qty {
+		qsz *= 2
+	}
+
+	q.Buf = make([]T, qsz)
+	for i := 0; i < qty; i++ {
+		q.Buf[i] = elem
+	}
+	q.Head = 0
+	q.Tail = qty & (qsz - 1)
+	q.Count = qty
+}
+
+// PushBack appends an element to the back of the queue.  Implements FIFO when
+// elements are removed with PopFront(), and LIFO when elements are removed
+// with PopBack().
+func (q *Duplexqueue[T]) PushBack(elem T) {
+	q.growIfFull()
+
+	q.Buf[q.Tail] = elem
+	// Calculate new Tail position.
+	q.Tail = q.next(q.Tail)
+	q.Count++
+}
+
+// PushFront prepends an element to the front of the queue.
+func (q *Duplexqueue[T]) PushFront(elem T) {
+	q.growIfFull()
+
+	// Calculate new Head position.
+	q.Head = q.prev(q.Head)
+	q.Buf[q.Head] = elem
+	q.Count++
+}
+
+// PushPop pops back (returns that value) and pushes elem at front, keeping
+// the length of the queue unchanged.  If the queue is empty, elem is pushed
+// and the zero value of T and false are returned.
+func (q *Duplexqueue[T]) PushPop(elem T) (T, bool) {
+	var zero T
+	if q.Count <= 0 {
+		q.PushFront(elem)
+		return zero, false
+	}
+
+	// Remove value at Tail.
+	q.Tail = q.prev(q.Tail)
+	ret := q.Buf[q.Tail]
+	q.Buf[q.Tail] = zero
+	// Calculate new Head position.
+	q.Head = q.prev(q.Head)
+	q.Buf[q.Head] = elem
+	return ret, true
+}
+
+// PopFront removes and returns the element from the front of the queue.
+// Implements FIFO when used with PushBack().  If the queue is empty, the
+// zero value of T and false are returned.
+func (q *Duplexqueue[T]) PopFront() (T, bool) {
+	var zero T
+	if q.Count <= 0 {
+		return zero, false
+	}
+	ret := q.Buf[q.Head]
+	q.Buf[q.Head] = zero
+	// Calculate new Head position.
+	q.Head = q.next(q.Head)
+	q.Count--
+
+	q.shrinkIfExcess()
+	return ret, true
+}
+
+// PopBack removes and returns the element from the back of the queue.
+// Implements LIFO when used with PushBack().  If the queue is empty, the
+// zero value of T and false are returned.
+func (q *Duplexqueue[T]) PopBack() (T, bool) {
+	var zero T
+	if q.Count <= 0 {
+		return zero, false
+	}
+
+	// Calculate new Tail position
+	q.Tail = q.prev(q.Tail)
+
+	// Remove value at Tail.
+	ret := q.Buf[q.Tail]
+	q.Buf[q.Tail] = zero
+	q.Count--
+
+	q.shrinkIfExcess()
+	return ret, true
+}
+
+// Front returns the element at the front of the queue.  This is the element
+// that would be returned by PopFront().
+func (q *Duplexqueue[T]) Front() (T, bool) {
+	if q.Count <= 0 {
+		var zero T
+		return zero, false
+	}
+	return q.Buf[q.Head], true
+}
+
+// Back returns the element at the back of the queue.  This is the element
+// that would be returned by PopBack().
+func (q *Duplexqueue[T]) Back() (T, bool) {
+	if q.Count <= 0 {
+		var zero T
+		return zero, false
+	}
+	return q.Buf[q.prev(q.Tail)], true
+}
+
+// At returns the element at index i in the queue without removing the element
+// from the queue.  This method accepts only non-negative index values.  At(0)
+// refers to the first element and is the same as Front().  At(Len()-1) refers
+// to the last element and is the same as Back().  If the index is invalid,
+// the zero value of T and false are returned.
+func (q *Duplexqueue[T]) At(i int) (T, bool) {
+	if i < 0 || i >= q.Count {
+		var zero T
+		return zero, false
+	}
+	// bitwise modulus
+	return q.Buf[(q.Head+i)&(len(q.Buf)-1)], true
+}
+
+// Index returns the element at index i taken modulo Len(), so that negative
+// values count from the back: Index(-1) is the same as Back().  Only an
+// empty queue returns false.
+func (q *Duplexqueue[T]) Index(i int) (T, bool) {
+	if q.Count <= 0 {
+		var zero T
+		return zero, false
+	}
+	i %= q.Count
+	if i < 0 {
+		i += q.Count
+	}
+	// bitwise modulus
+	return q.Buf[(q.Head+i)&(len(q.Buf)-1)], true
+}
+
+// Clear removes all elements from the queue, but retains the current capacity.
+// This is useful when repeatedly reusing the queue at high frequency to avoid
+// GC during reuse.  The queue will not be resized smaller as long as items are
+// only added.  Only when items are removed is the queue subject to getting
+// resized smaller.
+func (q *Duplexqueue[T]) Clear() {
+	var zero T
+	// bitwise modulus
+	modBits := len(q.Buf) - 1
+	for h, n := q.Head, 0; n < q.Count; h, n = (h+1)&modBits, n+1 {
+		q.Buf[h] = zero
+	}
+	q.Head = 0
+	q.Tail = 0
+	q.Count = 0
+}
+
+// Rotate rotates the duplexqueue n steps front-to-back.  If n is negative, rotates
+// back-to-front.  Having Duplexqueue provide Rotate() avoids resizing that could
+// happen if implementing rotation using only Pop and Push methods.
+func (q *Duplexqueue[T]) Rotate(n int) {
+	if q.Count <= 1 {
+		return
+	}
+	// Rotating a multiple of q.Count is same as no rotation.
+	n %= q.Count
+	if n == 0 {
+		return
+	}
+
+	var zero T
+	modBits := len(q.Buf) - 1
+	// If no empty space in buffer, only move Head and Tail indexes.
+	if q.Head == q.Tail {
+		// Calculate new Head and Tail using bitwise modulus.
+		q.Head = (q.Head + n) & modBits
+		q.Tail = (q.Tail + n) & modBits
+		return
+	}
+
+	if n < 0 {
+		// Rotate back to front.
+		for ; n < 0; n++ {
+			// Calculate new Head and Tail using bitwise modulus.
+			q.Head = (q.Head - 1) & modBits
+			q.Tail = (q.Tail - 1) & modBits
+			// Put Tail value at Head and remove value at Tail.
+			q.Buf[q.Head] = q.Buf[q.Tail]
+			q.Buf[q.Tail] = zero
+		}
+		return
+	}
+
+	// Rotate front to back.
+	for ; n > 0; n-- {
+		// Put Head value at Tail and remove value at Head.
+		q.Buf[q.Tail] = q.Buf[q.Head]
+		q.Buf[q.Head] = zero
+		// Calculate new Head and Tail using bitwise modulus.
+		q.Head = (q.Head + 1) & modBits
+		q.Tail = (q.Tail + 1) & modBits
+	}
+}
+
+// Do calls f for each element from front to back.
+func (q *Duplexqueue[T]) Do(f func(T)) {
+	for i := 0; i < q.Count; i++ {
+		f(q.Buf[(q.Head+i)&(len(q.Buf)-1)])
+	}
+}
+
+// DoIndex calls f for Len() elements starting at Index(idx), wrapping
+// around the back of the queue.
+func (q *Duplexqueue[T]) DoIndex(idx int, f func(T)) {
+	for i := 0; i < q.Count; i++ {
+		v, _ := q.Index(idx + i)
+		f(v)
+	}
+}
+
+// DoFor calls f for cnt elements starting at Index(idx), wrapping around
+// the back of the queue.
+func (q *Duplexqueue[T]) DoFor(idx int, cnt int, f func(T)) {
+	if q.Count <= 0 {
+		return
+	}
+	if cnt > q.Count {
+		cnt %= q.Count
+	}
+	for i := 0; i < cnt; i++ {
+		v, _ := q.Index(idx + i)
+		f(v)
+	}
+}
+
+// Slice returns a new slice holding the elements from front to back.
+func (q *Duplexqueue[T]) Slice() []T {
+	s := make([]T, q.Count)
+	for i := range s {
+		s[i] = q.Buf[(q.Head+i)&(len(q.Buf)-1)]
+	}
+	return s
+}
+
+// All returns an iterator over the index and element pairs of the queue, front
+// to back.  The queue must not be modified while iterating.
+func (q *Duplexqueue[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := 0; i < q.Count; i++ {
+			if !yield(i, q.Buf[(q.Head+i)&(len(q.Buf)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the index and element pairs of the queue,
+// back to front.
+func (q *Duplexqueue[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := q.Count - 1; i >= 0; i-- {
+			if !yield(i, q.Buf[(q.Head+i)&(len(q.Buf)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the elements of the queue, front to back.
+func (q *Duplexqueue[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < q.Count; i++ {
+			if !yield(q.Buf[(q.Head+i)&(len(q.Buf)-1)]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, encoding the queue as an array of its
+// elements from front to back. The buffer layout, with its empty slots and
+// Head and Tail positions, is not part of the encoding.
+func (q Duplexqueue[T]) MarshalJSON() ([]byte, error) {
+	values := make([]T, 0, q.Count)
+	for v := range q.Values() {
+		values = append(values, v)
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the contents of the
+// queue with the elements of a JSON array, from front to back.
+func (q *Duplexqueue[T]) UnmarshalJSON(data []byte) error {
+	var values []T
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	var restored Duplexqueue[T]
+	for _, v := range values {
+		restored.PushBack(v)
+	}
+	*q = restored
+	return nil
+}
+
+// prev returns the previous buffer position wrapping around buffer.
+func (q *Duplexqueue[T]) prev(i int) int {
+	return (i - 1) & (len(q.Buf) - 1) // bitwise modulus
+}
+
+// next returns the next buffer position wrapping around buffer.
+func (q *Duplexqueue[T]) next(i int) int {
+	return (i + 1) & (len(q.Buf) - 1) // bitwise modulus
+}
+
+// growIfFull resizes up if the buffer is full.
+func (q *Duplexqueue[T]) growIfFull() {
+	if len(q.Buf) == 0 {
+		q.Buf = make([]T, minCapacity)
+		return
+	}
+	if q.Count == len(q.Buf) {
+		q.resize()
+	}
+}
+
+// shrinkIfExcess resize down if the buffer 1/4 full.
+func (q *Duplexqueue[T]) shrinkIfExcess() {
+	if len(q.Buf) > minCapacity && (q.Count<<2) == len(q.Buf) {
+		q.resize()
+	}
+}
+
+// resize resizes the duplexqueue to fit exactly twice its current contents.  This is
+// used to grow the queue when it is full, and also to shrink it when it is
+// only a quarter full.
+func (q *Duplexqueue[T]) resize() {
+	newBuf := make([]T, q.Count<<1)
+	if q.Tail > q.Head {
+		copy(newBuf, q.Buf[q.Head:q.Tail])
+	} else {
+		n := copy(newBuf, q.Buf[q.Head:])
+		copy(newBuf[n:], q.Buf[:q.Tail])
+	}
+
+	q.Head = 0
+	q.Tail = q.Count
+	q.Buf = newBuf
+}
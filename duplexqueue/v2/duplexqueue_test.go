@@ -0,0 +1,224 @@
+package duplexqueue
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+type measure struct {
+	MsgNum  int  `json:"msgNum"`
+	IsAlert bool `json:"isAlert"`
+}
+
+func TestEmpty(t *testing.T) {
+	var q Duplexqueue[int]
+	if q.Len() != 0 {
+		t.Error("q.Len() =", q.Len(), "expect 0")
+	}
+	if _, ok := q.Front(); ok {
+		t.Error("Front() on empty queue returned ok")
+	}
+	if _, ok := q.Back(); ok {
+		t.Error("Back() on empty queue returned ok")
+	}
+	if _, ok := q.PopFront(); ok {
+		t.Error("PopFront() on empty queue returned ok")
+	}
+	if _, ok := q.PopBack(); ok {
+		t.Error("PopBack() on empty queue returned ok")
+	}
+	if _, ok := q.Index(3); ok {
+		t.Error("Index() on empty queue returned ok")
+	}
+}
+
+func TestFrontBack(t *testing.T) {
+	var q Duplexqueue[string]
+	q.PushBack("foo")
+	q.PushBack("bar")
+	q.PushBack("baz")
+	if v, _ := q.Front(); v != "foo" {
+		t.Error("wrong value at front of queue")
+	}
+	if v, _ := q.Back(); v != "baz" {
+		t.Error("wrong value at back of queue")
+	}
+	if v, _ := q.PopFront(); v != "foo" {
+		t.Error("wrong value removed from front of queue")
+	}
+	if v, _ := q.PopBack(); v != "baz" {
+		t.Error("wrong value removed from back of queue")
+	}
+	if v, _ := q.Front(); v != "bar" {
+		t.Error("wrong value remaining at front of queue")
+	}
+}
+
+func TestInit(t *testing.T) {
+	var q Duplexqueue[int]
+	for _, qty := range []int{0, 1, 3, 4, 5, 8, 100} {
+		q.Init(qty, 7)
+		if q.Len() != qty {
+			t.Error("Init", qty, "has length", q.Len())
+		}
+		q.PushBack(9)
+		if v, _ := q.Back(); v != 9 {
+			t.Error("Init", qty, "then PushBack has back", v)
+		}
+		if v, _ := q.Front(); qty > 0 && v != 7 {
+			t.Error("Init", qty, "has front", v)
+		}
+	}
+}
+
+func TestPushPop(t *testing.T) {
+	var q Duplexqueue[int]
+	if _, ok := q.PushPop(1); ok {
+		t.Error("PushPop() on empty queue returned ok")
+	}
+	q.PushBack(2)
+	q.PushBack(3)
+	// 1 2 3 -> 0 1 2
+	if v, ok := q.PushPop(0); !ok || v != 3 {
+		t.Error("PushPop() returned", v, ok, "expected 3")
+	}
+	for i := 0; i < 3; i++ {
+		if v, _ := q.At(i); v != i {
+			t.Error("At", i, "has", v)
+		}
+	}
+	if q.Len() != 3 {
+		t.Error("PushPop() changed length to", q.Len())
+	}
+}
+
+func TestIndex(t *testing.T) {
+	var q Duplexqueue[int]
+	for i := 0; i < 10; i++ {
+		q.PushBack(i)
+	}
+	cases := map[int]int{0: 0, 3: 3, 10: 0, 13: 3, -1: 9, -10: 0, -13: 7}
+	for i, want := range cases {
+		if v, _ := q.Index(i); v != want {
+			t.Error("Index", i, "is", v, "expected", want)
+		}
+	}
+
+	var got []int
+	q.DoFor(-2, 4, func(v int) { got = append(got, v) })
+	if len(got) != 4 || got[0] != 8 || got[1] != 9 || got[2] != 0 || got[3] != 1 {
+		t.Error("DoFor(-2, 4) visited", got)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	var q Duplexqueue[int]
+	for i := 0; i < 6; i++ {
+		q.PushBack(i)
+	}
+	q.PopFront()
+	q.PopFront()
+	q.PushBack(6)
+	q.PushBack(7)
+	s := q.Slice()
+	for i, v := range s {
+		if v != i+2 {
+			t.Error("Slice()", s, "not in order")
+			break
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var q Duplexqueue[measure]
+	for i := 0; i < 5; i++ {
+		q.PushBack(measure{MsgNum: i, IsAlert: i%2 == 1})
+	}
+	q.PopFront()
+
+	b, err := json.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Duplexqueue[measure]
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != q.Len() {
+		t.Fatal("restored queue has length", r.Len(), "expected", q.Len())
+	}
+	for i := 0; i < q.Len(); i++ {
+		a, _ := q.At(i)
+		b, _ := r.At(i)
+		if a != b {
+			t.Error("At", i, "restored", b, "expected", a)
+		}
+	}
+	r.PushBack(measure{MsgNum: 5})
+	if v, _ := r.Back(); v.MsgNum != 5 {
+		t.Error("restored queue not usable")
+	}
+}
+
+func BenchmarkSerial(b *testing.B) {
+	var q Duplexqueue[int]
+	for i := 0; i < b.N; i++ {
+		q.PushBack(i)
+	}
+	for i := 0; i < b.N; i++ {
+		q.PopFront()
+	}
+}
+
+func TestIterators(t *testing.T) {
+	var q Duplexqueue[string]
+	for _, s := range []string{"b", "c", "d"} {
+		q.PushBack(s)
+	}
+	q.PushFront("a")
+	if got := slices.Collect(q.Values()); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Error("Values() collected", got)
+	}
+	var back []string
+	for i, s := range q.Backward() {
+		if v, _ := q.At(i); v != s {
+			t.Error("Backward() gave", s, "at", i)
+		}
+		back = append(back, s)
+	}
+	if !slices.Equal(back, []string{"d", "c", "b", "a"}) {
+		t.Error("Backward() visited", back)
+	}
+	for i := range q.All() {
+		if i == 1 {
+			break
+		}
+		if i > 1 {
+			t.Error("All() did not stop at break")
+		}
+	}
+}
+
+func TestJSONLogicalOrder(t *testing.T) {
+	var q Duplexqueue[int]
+	for i := 0; i < 6; i++ {
+		q.PushBack(i)
+	}
+	q.Rotate(2)
+	b, err := json.Marshal(q)
+	if err != nil || string(b) != "[2,3,4,5,0,1]" {
+		t.Fatalf("json.Marshal gave %s, %v", b, err)
+	}
+	b, err = json.Marshal(struct{ Q Duplexqueue[int] }{q})
+	if err != nil || string(b) != `{"Q":[2,3,4,5,0,1]}` {
+		t.Errorf("json.Marshal of struct field gave %s, %v", b, err)
+	}
+	var r Duplexqueue[int]
+	if err := json.Unmarshal([]byte("[7,8]"), &r); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r.Front(); v != 7 || r.Len() != 2 {
+		t.Error("json.Unmarshal gave", r.Slice())
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package durable provides a FIFO queue that survives process restarts.
+// The elements live in a duplexqueue ring, which serves every read, and
+// every PushBack and PopFront is first appended to a write-ahead log on
+// disk. The log is split in segment files of numbered records, each with
+// a CRC-32 checksum; opening the queue replays the segments to rebuild
+// the ring, discarding a torn record at the end of the last one.
+// Compaction writes the live elements to a fresh segment and deletes the
+// older ones, so the log does not grow without bound.
+package durable
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrCorrupt is returned by Open when a segment other than the last one
+// holds a record that fails its checksum or is cut short.
+var ErrCorrupt = errors.New("durable: corrupt log segment")
+
+// ErrClosed is returned by the methods of a closed queue.
+var ErrClosed = errors.New("durable: queue closed")
+
+// ErrFailed is wrapped in the errors returned by the methods of a queue
+// whose log was left in an unknown state by a failed write, sync or
+// compaction. The queue must be closed and opened again.
+var ErrFailed = errors.New("durable: log failed")
+
+// Codec converts elements to and from the bytes stored in the log.
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(b []byte) (T, error)
+}
+
+// JSONCodec stores elements with encoding/json.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(v T) ([]byte, error) { return json.Marshal(v) }
+
+func (JSONCodec[T]) Decode(b []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// BytesCodec stores []byte elements as they are.
+type BytesCodec struct{}
+
+func (BytesCodec) Encode(v []byte) ([]byte, error) { return v, nil }
+
+func (BytesCodec) Decode(b []byte) ([]byte, error) { return append([]byte(nil), b...), nil }
+
+// StringCodec stores string elements as their bytes.
+type StringCodec struct{}
+
+func (StringCodec) Encode(v string) ([]byte, error) { return []byte(v), nil }
+
+func (StringCodec) Decode(b []byte) (string, error) { return string(b), nil }
@@ -0,0 +1,504 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package durable
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gus-maurizio/structures/duplexqueue/v2"
+)
+
+// SyncPolicy says when the log is flushed to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes after every record; nothing acknowledged is
+	// lost in a crash.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval flushes in the background at a fixed interval; a
+	// crash loses at most the records of the last interval.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+const (
+	defaultSegmentSize  = 16 << 20
+	defaultSyncInterval = time.Second
+)
+
+// Queue is a durable FIFO queue safe for use by several goroutines.
+type Queue[T any] struct {
+	mu    sync.Mutex
+	dir   string
+	codec Codec[T]
+	q     duplexqueue.Duplexqueue[T]
+
+	f        *os.File
+	seq      uint64   // segment being appended to
+	size     int64    // bytes in segment seq
+	segments []uint64 // segments making up the log, oldest first
+	dirty    bool     // records written since the last sync
+	records  int      // records written since the last compaction
+	closed   bool
+
+	policy       SyncPolicy
+	interval     time.Duration
+	segmentSize  int64
+	compactEvery int
+	compactError func(error)
+	failed       error // set once the log can no longer be appended to
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// Option configures a Queue when passed to Open.
+type Option[T any] func(*Queue[T])
+
+// WithSync sets the sync policy, SyncAlways by default. The interval is
+// used by SyncInterval; 0 means one second.
+func WithSync[T any](policy SyncPolicy, interval time.Duration) Option[T] {
+	return func(q *Queue[T]) {
+		q.policy = policy
+		if interval > 0 {
+			q.interval = interval
+		}
+	}
+}
+
+// WithSegmentSize starts a new segment once the current one holds at
+// least n bytes, 16MiB by default.
+func WithSegmentSize[T any](n int64) Option[T] {
+	return func(q *Queue[T]) { q.segmentSize = n }
+}
+
+// WithCompactEvery compacts the log after every n records written. 0, the
+// default, leaves compaction to Compact.
+func WithCompactEvery[T any](n int) Option[T] {
+	return func(q *Queue[T]) { q.compactEvery = n }
+}
+
+// WithCompactError registers f to be called with the error of a failed
+// compaction triggered by WithCompactEvery. Such a failure does not fail
+// the PushBack or PopFront that triggered it, which has been logged.
+func WithCompactError[T any](f func(error)) Option[T] {
+	return func(q *Queue[T]) { q.compactError = f }
+}
+
+// Open opens the queue stored in dir, creating dir if needed, and rebuilds
+// its elements from the log.
+func Open[T any](dir string, codec Codec[T], opts ...Option[T]) (*Queue[T], error) {
+	q := &Queue[T]{
+		dir:         dir,
+		codec:       codec,
+		interval:    defaultSyncInterval,
+		segmentSize: defaultSegmentSize,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := q.recover(); err != nil {
+		return nil, err
+	}
+	if q.policy == SyncInterval {
+		q.stop = make(chan struct{})
+		q.done = make(chan struct{})
+		go q.syncLoop()
+	}
+	return q, nil
+}
+
+// recover replays the segments in dir and opens the last one for append.
+func (q *Queue[T]) recover() error {
+	os.Remove(filepath.Join(q.dir, compactTemp))
+	seqs, err := listSegments(q.dir)
+	if err != nil {
+		return err
+	}
+
+	type segment struct {
+		seq   uint64
+		recs  []record
+		valid int
+		size  int
+	}
+	segs := make([]segment, len(seqs))
+	start := 0
+	for i, seq := range seqs {
+		data, err := os.ReadFile(segmentPath(q.dir, seq))
+		if err != nil {
+			return err
+		}
+		recs, valid := parseRecords(data)
+		segs[i] = segment{seq, recs, valid, len(data)}
+		if len(recs) > 0 && recs[0].op == opSnapshot {
+			start = i
+		}
+	}
+	// Segments before the last snapshot are left over from a compaction
+	// interrupted before it deleted them.
+	for _, s := range segs[:start] {
+		if err := os.Remove(segmentPath(q.dir, s.seq)); err != nil {
+			return err
+		}
+	}
+	segs = segs[start:]
+
+	for i, s := range segs {
+		if s.valid < s.size {
+			if i < len(segs)-1 {
+				return fmt.Errorf("%w: %s at offset %d", ErrCorrupt, segmentName(s.seq), s.valid)
+			}
+			// A torn write at the end of the log: drop it.
+			if err := os.Truncate(segmentPath(q.dir, s.seq), int64(s.valid)); err != nil {
+				return err
+			}
+		}
+		for _, r := range s.recs {
+			switch r.op {
+			case opPush:
+				v, err := q.codec.Decode(r.payload)
+				if err != nil {
+					return fmt.Errorf("durable: decoding %s: %w", segmentName(s.seq), err)
+				}
+				q.q.PushBack(v)
+			case opPop:
+				q.q.PopFront()
+			}
+		}
+		q.segments = append(q.segments, s.seq)
+	}
+
+	if len(q.segments) == 0 {
+		return q.openSegment(1)
+	}
+	last := q.segments[len(q.segments)-1]
+	f, err := os.OpenFile(segmentPath(q.dir, last), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	q.f, q.seq, q.size = f, last, st.Size()
+	return nil
+}
+
+// openSegment creates segment seq and makes it the one appended to.
+func (q *Queue[T]) openSegment(seq uint64) error {
+	f, err := os.OpenFile(segmentPath(q.dir, seq), os.O_WRONLY|os.O_CREATE|os.O_APPEND|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(q.dir); err != nil {
+		f.Close()
+		os.Remove(segmentPath(q.dir, seq))
+		return err
+	}
+	q.f, q.seq, q.size = f, seq, 0
+	q.segments = append(q.segments, seq)
+	return nil
+}
+
+// write appends a record to the log, applying the sync policy, rolling
+// over to a new segment first if the current one is full. If it returns
+// an error the record is not in the log.
+func (q *Queue[T]) write(op byte, payload []byte) error {
+	if q.failed != nil {
+		return q.failed
+	}
+	if q.size >= q.segmentSize {
+		if err := q.roll(); err != nil {
+			return err
+		}
+	}
+	b := appendRecord(make([]byte, 0, headerSize+len(payload)), op, payload)
+	if _, err := q.f.Write(b); err != nil {
+		// Cut off whatever part of the record was written, or records
+		// appended after it would be lost at the torn offset on recovery.
+		if terr := q.f.Truncate(q.size); terr != nil {
+			return q.fail(terr)
+		}
+		return err
+	}
+	q.size += int64(len(b))
+	q.dirty = true
+	q.records++
+	if q.policy == SyncAlways {
+		if err := q.sync(); err != nil {
+			// The record may or may not be on disk.
+			return q.fail(err)
+		}
+	}
+	return nil
+}
+
+// roll syncs the current segment and starts the next one. The current
+// segment stays open for appending until the next one is ready.
+func (q *Queue[T]) roll() error {
+	if err := q.sync(); err != nil {
+		return q.fail(err)
+	}
+	old := q.f
+	if err := q.openSegment(q.seq + 1); err != nil {
+		return err
+	}
+	old.Close()
+	return nil
+}
+
+// fail marks the log as unusable after err left it in an unknown state;
+// every later write returns the result.
+func (q *Queue[T]) fail(err error) error {
+	q.failed = fmt.Errorf("%w: %w", ErrFailed, err)
+	return q.failed
+}
+
+// sync flushes the current segment if it has unsynced records. The
+// records stay unsynced if it fails, so the next sync tries again.
+func (q *Queue[T]) sync() error {
+	if !q.dirty {
+		return nil
+	}
+	if err := q.f.Sync(); err != nil {
+		return err
+	}
+	q.dirty = false
+	return nil
+}
+
+func (q *Queue[T]) syncLoop() {
+	defer close(q.done)
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+			q.mu.Lock()
+			if !q.closed && q.failed == nil {
+				// Nobody is waiting for this flush to report to, so
+				// fail the log rather than acknowledge more records.
+				if err := q.sync(); err != nil {
+					q.fail(err)
+				}
+			}
+			q.mu.Unlock()
+		}
+	}
+}
+
+// Len returns the number of elements in the queue.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.q.Len()
+}
+
+// Front returns the element that PopFront would remove, false if the
+// queue is empty.
+func (q *Queue[T]) Front() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.q.Front()
+}
+
+// At returns the element at index i, false if i is out of range.
+func (q *Queue[T]) At(i int) (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.q.At(i)
+}
+
+// PushBack logs v and appends it to the back of the queue. If logging
+// fails the queue is unchanged.
+func (q *Queue[T]) PushBack(v T) error {
+	b, err := q.codec.Encode(v)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if err := q.write(opPush, b); err != nil {
+		return err
+	}
+	q.q.PushBack(v)
+	q.compactIfDue()
+	return nil
+}
+
+// PopFront logs the removal of the front element and returns it. It
+// returns false if the queue is empty. If logging fails the queue is
+// unchanged.
+func (q *Queue[T]) PopFront() (T, bool, error) {
+	var zero T
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return zero, false, ErrClosed
+	}
+	if q.q.Len() == 0 {
+		return zero, false, nil
+	}
+	if err := q.write(opPop, nil); err != nil {
+		return zero, false, err
+	}
+	v, _ := q.q.PopFront()
+	q.compactIfDue()
+	return v, true, nil
+}
+
+// Sync flushes the log to stable storage whatever the sync policy.
+func (q *Queue[T]) Sync() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if q.failed != nil {
+		return q.failed
+	}
+	return q.sync()
+}
+
+// compactIfDue compacts the log if WithCompactEvery records have been
+// written, reporting a failure to the WithCompactError callback.
+func (q *Queue[T]) compactIfDue() {
+	if q.compactEvery > 0 && q.records >= q.compactEvery {
+		if err := q.compact(); err != nil && q.compactError != nil {
+			q.compactError(err)
+		}
+	}
+}
+
+// Compact rewrites the log as a single segment holding the elements in
+// the queue, and deletes the segments it replaces.
+func (q *Queue[T]) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	return q.compact()
+}
+
+// compactTemp is the file a compaction writes before renaming it into a
+// segment.
+const compactTemp = "compact.tmp"
+
+func (q *Queue[T]) compact() error {
+	if q.failed != nil {
+		return q.failed
+	}
+	b := appendRecord(nil, opSnapshot, nil)
+	var err error
+	q.q.Do(func(v T) {
+		if err != nil {
+			return
+		}
+		var p []byte
+		if p, err = q.codec.Encode(v); err == nil {
+			b = appendRecord(b, opPush, p)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// The snapshot becomes visible only once complete and synced, so a
+	// crash leaves either the old segments or the new one to replay.
+	tmp := filepath.Join(q.dir, compactTemp)
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	next := q.seq + 1
+	if err := os.Rename(tmp, segmentPath(q.dir, next)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// From here on recovery starts at the new segment, so records can no
+	// longer go to the old ones: if it cannot be used the log has failed.
+	if err := syncDir(q.dir); err != nil {
+		return q.fail(err)
+	}
+	f, err = os.OpenFile(segmentPath(q.dir, next), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.f.Close()
+	for _, seq := range q.segments {
+		os.Remove(segmentPath(q.dir, seq))
+	}
+	q.f, q.seq, q.size = f, next, int64(len(b))
+	q.segments = []uint64{next}
+	q.dirty = false
+	q.records = 0
+	return nil
+}
+
+// Close flushes the log and closes the queue. If the log has failed it
+// returns the failure.
+func (q *Queue[T]) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrClosed
+	}
+	q.closed = true
+	err := q.failed
+	if err == nil {
+		err = q.sync()
+	}
+	if cerr := q.f.Close(); err == nil {
+		err = cerr
+	}
+	q.mu.Unlock()
+	if q.stop != nil {
+		close(q.stop)
+		<-q.done
+	}
+	return err
+}
@@ -0,0 +1,344 @@
+package durable
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+type job struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func open(t *testing.T, dir string, opts ...Option[job]) *Queue[job] {
+	t.Helper()
+	q, err := Open[job](dir, JSONCodec[job]{}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// contents pops every element of q.
+func contents(t *testing.T, q *Queue[job]) []int {
+	t.Helper()
+	var ids []int
+	for {
+		v, ok, err := q.PopFront()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			return ids
+		}
+		ids = append(ids, v.ID)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir)
+	for i := 0; i < 10; i++ {
+		if err := q.PushBack(job{ID: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if v, ok, _ := q.PopFront(); !ok || v.ID != i {
+			t.Fatal("PopFront() =", v, ok)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.PushBack(job{}); err != ErrClosed {
+		t.Error("PushBack() after Close returned", err)
+	}
+
+	q = open(t, dir)
+	defer q.Close()
+	if q.Len() != 6 {
+		t.Fatal("reopened queue has length", q.Len())
+	}
+	if v, _ := q.Front(); v.ID != 4 {
+		t.Error("reopened queue front", v)
+	}
+	if got := contents(t, q); !slices.Equal(got, []int{4, 5, 6, 7, 8, 9}) {
+		t.Error("reopened queue holds", got)
+	}
+}
+
+func TestTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir)
+	q.PushBack(job{ID: 1})
+	q.PushBack(job{ID: 2})
+	q.Close()
+
+	// A crash in the middle of a record leaves a partial one at the end.
+	seqs, _ := listSegments(dir)
+	path := segmentPath(dir, seqs[len(seqs)-1])
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write(appendRecord(nil, opPush, []byte(`{"id":3}`))[:12])
+	f.Close()
+
+	q = open(t, dir)
+	q.PushBack(job{ID: 4})
+	q.Close()
+	q = open(t, dir)
+	defer q.Close()
+	if got := contents(t, q); !slices.Equal(got, []int{1, 2, 4}) {
+		t.Error("queue after torn write holds", got)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir, WithSegmentSize[job](64))
+	for i := 0; i < 10; i++ {
+		q.PushBack(job{ID: i, Name: "segment roll"})
+	}
+	q.Close()
+	seqs, _ := listSegments(dir)
+	if len(seqs) < 3 {
+		t.Fatal("expected several segments, got", len(seqs))
+	}
+
+	// Flip a payload byte in the first segment.
+	path := segmentPath(dir, seqs[0])
+	data, _ := os.ReadFile(path)
+	data[headerSize+2] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+	if _, err := Open[job](dir, JSONCodec[job]{}); !errors.Is(err, ErrCorrupt) {
+		t.Error("Open() with corrupt segment returned", err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir, WithSegmentSize[job](128))
+	for i := 0; i < 50; i++ {
+		q.PushBack(job{ID: i})
+	}
+	for i := 0; i < 45; i++ {
+		q.PopFront()
+	}
+	before, _ := listSegments(dir)
+	if err := q.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := listSegments(dir)
+	if len(after) != 1 || len(before) <= 1 || after[0] <= before[len(before)-1] {
+		t.Error("segments", before, "compacted to", after)
+	}
+	q.PushBack(job{ID: 50})
+	q.Close()
+
+	q = open(t, dir)
+	defer q.Close()
+	if got := contents(t, q); !slices.Equal(got, []int{45, 46, 47, 48, 49, 50}) {
+		t.Error("compacted queue holds", got)
+	}
+}
+
+func TestInterruptedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir)
+	q.PushBack(job{ID: 1})
+	q.PushBack(job{ID: 2})
+	q.PopFront()
+	q.Close()
+
+	// A compaction that renamed its snapshot but did not get to delete
+	// the old segment, and a leftover temporary file.
+	snap := appendRecord(nil, opSnapshot, nil)
+	snap = appendRecord(snap, opPush, []byte(`{"id":2}`))
+	os.WriteFile(segmentPath(dir, 2), snap, 0o644)
+	os.WriteFile(filepath.Join(dir, compactTemp), []byte("junk"), 0o644)
+
+	q = open(t, dir)
+	defer q.Close()
+	if got := contents(t, q); !slices.Equal(got, []int{2}) {
+		t.Error("queue holds", got)
+	}
+	if seqs, _ := listSegments(dir); len(seqs) != 1 || seqs[0] != 2 {
+		t.Error("segments left", seqs)
+	}
+}
+
+func TestAutoCompactAndSyncPolicies(t *testing.T) {
+	for _, policy := range []SyncPolicy{SyncAlways, SyncInterval, SyncNever} {
+		dir := t.TempDir()
+		q := open(t, dir,
+			WithSync[job](policy, time.Millisecond),
+			WithCompactEvery[job](20),
+			WithSegmentSize[job](256))
+		for i := 0; i < 100; i++ {
+			q.PushBack(job{ID: i})
+			if i%2 == 1 {
+				q.PopFront()
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if err := q.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		q.Close()
+
+		q = open(t, dir)
+		if q.Len() != 50 {
+			t.Error("policy", policy, "reopened with length", q.Len())
+		}
+		if v, _ := q.Front(); v.ID != 50 {
+			t.Error("policy", policy, "reopened with front", v)
+		}
+		q.Close()
+	}
+}
+
+func BenchmarkPushPopNoSync(b *testing.B) {
+	q, err := Open[[]byte](b.TempDir(), BytesCodec{}, WithSync[[]byte](SyncNever, 0))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer q.Close()
+	item := make([]byte, 128)
+	for i := 0; i < b.N; i++ {
+		q.PushBack(item)
+		q.PopFront()
+	}
+}
+
+func TestRollFailure(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir, WithSegmentSize[job](1))
+	q.PushBack(job{ID: 1})
+	// Block the next segment so that rolling over to it fails.
+	blocker := segmentPath(dir, q.seq+1)
+	os.Mkdir(blocker, 0o755)
+	if err := q.PushBack(job{ID: 2}); err == nil {
+		t.Fatal("PushBack succeeded without a segment to write to")
+	}
+	if q.Len() != 1 {
+		t.Error("failed PushBack changed the queue")
+	}
+	os.Remove(blocker)
+	if err := q.PushBack(job{ID: 3}); err != nil {
+		t.Fatal("PushBack after the segment was freed:", err)
+	}
+	q.Close()
+	q = open(t, dir)
+	defer q.Close()
+	if got := contents(t, q); !slices.Equal(got, []int{1, 3}) {
+		t.Error("reopened queue holds", got)
+	}
+}
+
+func TestCompactFailureDoesNotFailPush(t *testing.T) {
+	dir := t.TempDir()
+	var compactErrs []error
+	q := open(t, dir, WithCompactEvery[job](2), WithCompactError[job](func(err error) {
+		compactErrs = append(compactErrs, err)
+	}))
+	os.Mkdir(filepath.Join(dir, compactTemp), 0o755)
+	for i := 1; i <= 3; i++ {
+		if err := q.PushBack(job{ID: i}); err != nil {
+			t.Fatal("PushBack returned", err)
+		}
+	}
+	if _, _, err := q.PopFront(); err != nil {
+		t.Fatal("PopFront returned", err)
+	}
+	if len(compactErrs) == 0 {
+		t.Error("compaction failure not reported")
+	}
+	q.Close()
+	os.Remove(filepath.Join(dir, compactTemp))
+	q = open(t, dir)
+	defer q.Close()
+	if got := contents(t, q); !slices.Equal(got, []int{2, 3}) {
+		t.Error("reopened queue holds", got)
+	}
+}
+
+func TestFailedLog(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir)
+	defer q.Close()
+	q.PushBack(job{ID: 1})
+	// Swap in a handle that can neither be written nor truncated.
+	ro, err := os.Open(segmentPath(dir, q.seq))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.f.Close()
+	q.f = ro
+	if err := q.PushBack(job{ID: 2}); !errors.Is(err, ErrFailed) {
+		t.Fatal("PushBack on a broken log returned", err)
+	}
+	if _, _, err := q.PopFront(); !errors.Is(err, ErrFailed) {
+		t.Error("PopFront on a failed log returned", err)
+	}
+	if err := q.Compact(); !errors.Is(err, ErrFailed) {
+		t.Error("Compact on a failed log returned", err)
+	}
+	if q.Len() != 1 {
+		t.Error("failed log changed the queue, Len", q.Len())
+	}
+}
+
+func TestSyncFailure(t *testing.T) {
+	// Swap in a closed handle, which cannot be synced, with records
+	// waiting to be flushed.
+	broken := func(q *Queue[job]) {
+		f, err := os.Open(segmentPath(q.dir, q.seq))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		q.mu.Lock()
+		q.f.Close()
+		q.f = f
+		q.dirty = true
+		q.mu.Unlock()
+	}
+
+	q := open(t, t.TempDir(), WithSync[job](SyncInterval, time.Hour))
+	q.PushBack(job{ID: 1})
+	broken(q)
+	for i := 0; i < 2; i++ {
+		if err := q.Sync(); err == nil {
+			t.Fatal("Sync of a broken log returned nil, attempt", i+1)
+		}
+	}
+	q.Close()
+
+	q = open(t, t.TempDir(), WithSync[job](SyncInterval, time.Millisecond))
+	broken(q)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		q.mu.Lock()
+		failed := q.failed
+		q.mu.Unlock()
+		if failed != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background sync failure not recorded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := q.Sync(); !errors.Is(err, ErrFailed) {
+		t.Error("Sync after a background failure returned", err)
+	}
+	if err := q.PushBack(job{ID: 2}); !errors.Is(err, ErrFailed) {
+		t.Error("PushBack after a background failure returned", err)
+	}
+	if err := q.Close(); !errors.Is(err, ErrFailed) {
+		t.Error("Close after a background failure returned", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package durable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Record layout: payload length (uint32), CRC-32 of op and payload
+// (uint32), op (1 byte), payload. Integers are little endian.
+const headerSize = 9
+
+const (
+	// opPush appends its payload, an encoded element.
+	opPush byte = 1
+	// opPop removes the front element.
+	opPop byte = 2
+	// opSnapshot starts a compacted segment: the state is rebuilt from
+	// this segment on and earlier segments are ignored.
+	opSnapshot byte = 3
+)
+
+const segmentExt = ".wal"
+
+// record is a decoded log record.
+type record struct {
+	op      byte
+	payload []byte
+}
+
+// appendRecord appends the encoding of a record to b.
+func appendRecord(b []byte, op byte, payload []byte) []byte {
+	crc := crc32.NewIEEE()
+	crc.Write([]byte{op})
+	crc.Write(payload)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(payload)))
+	b = binary.LittleEndian.AppendUint32(b, crc.Sum32())
+	b = append(b, op)
+	return append(b, payload...)
+}
+
+// parseRecords decodes the records in data. It returns the records up to
+// the first one that is cut short or fails its checksum, and the offset
+// where that one starts, which is len(data) if all of them are valid.
+func parseRecords(data []byte) ([]record, int) {
+	var recs []record
+	off := 0
+	for off < len(data) {
+		if len(data)-off < headerSize {
+			break
+		}
+		n := int(binary.LittleEndian.Uint32(data[off:]))
+		sum := binary.LittleEndian.Uint32(data[off+4:])
+		op := data[off+8]
+		if n > len(data)-off-headerSize {
+			break
+		}
+		payload := data[off+headerSize : off+headerSize+n]
+		crc := crc32.NewIEEE()
+		crc.Write([]byte{op})
+		crc.Write(payload)
+		if crc.Sum32() != sum || op < opPush || op > opSnapshot {
+			break
+		}
+		recs = append(recs, record{op, payload})
+		off += headerSize + n
+	}
+	return recs, off
+}
+
+// segmentName returns the file name of segment seq.
+func segmentName(seq uint64) string {
+	return fmt.Sprintf("%016d%s", seq, segmentExt)
+}
+
+// listSegments returns the sequence numbers of the segments in dir, in
+// increasing order.
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	return seqs, nil
+}
+
+// syncDir flushes the directory entry changes of dir to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// segmentPath returns the path of segment seq in dir.
+func segmentPath(dir string, seq uint64) string {
+	return filepath.Join(dir, segmentName(seq))
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package broadcast provides the wake-up signal shared by the blocking
+// structures in this module: goroutines waiting for a condition guarded
+// by a mutex, and the goroutine that may have made it true.
+package broadcast
+
+// Signal wakes every goroutine waiting on it at once. The channel is
+// created by the first waiter and closed, then cleared, by Broadcast, so
+// a Signal nobody waits on costs nothing. The zero value is ready to use.
+// Its methods must be called with the owner's lock held.
+type Signal struct {
+	ch chan struct{}
+}
+
+// Wait returns a channel that is closed by the next Broadcast. The caller
+// releases its lock before receiving from it.
+func (s *Signal) Wait() <-chan struct{} {
+	if s.ch == nil {
+		s.ch = make(chan struct{})
+	}
+	return s.ch
+}
+
+// Broadcast wakes the goroutines waiting on the channel returned by Wait,
+// if any.
+func (s *Signal) Broadcast() {
+	if s.ch != nil {
+		close(s.ch)
+		s.ch = nil
+	}
+}
@@ -0,0 +1,29 @@
+package broadcast
+
+import "testing"
+
+func TestBroadcast(t *testing.T) {
+	var s Signal
+	s.Broadcast() // no waiters: nothing to do
+	a, b := s.Wait(), s.Wait()
+	if a != b {
+		t.Fatal("waiters before a Broadcast got different channels")
+	}
+	s.Broadcast()
+	for _, ch := range []<-chan struct{}{a, b} {
+		select {
+		case <-ch:
+		default:
+			t.Fatal("waiter not woken by Broadcast")
+		}
+	}
+	c := s.Wait()
+	if c == a {
+		t.Fatal("Wait after Broadcast returned the closed channel")
+	}
+	select {
+	case <-c:
+		t.Fatal("new channel closed before the next Broadcast")
+	default:
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package mmapring keeps the last N fixed-size records in a memory mapped
+// file, with the semantics of circularbuffer: the ring is always full, each
+// Push drops the oldest record, and Get and GetValues see the records
+// oldest first. Records survive restarts, and other processes can follow
+// the ring with a Reader while one writer pushes to it.
+//
+// The file is a 64 byte header followed by capacity records:
+//
+//	offset  size  field
+//	0       4     magic "MRNG"
+//	4       4     format version
+//	8       8     capacity, in records
+//	16      8     record size, in bytes
+//	24      8     head, the position of the oldest record
+//	32      8     generation, twice the number of pushes, odd during a push
+//
+// Integers are in host byte order, so a file is not portable between
+// machines of different endianness. The generation works as a sequence
+// lock: a Reader retries a read that overlapped a push.
+package mmapring
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	magic      = "MRNG"
+	version    = 1
+	headerSize = 64
+
+	offVersion    = 4
+	offCapacity   = 8
+	offRecordSize = 16
+	offHead       = 24
+	offGeneration = 32
+)
+
+// readRetries bounds how many times a Reader retries a read that keeps
+// overlapping a push before giving up with ErrBusy.
+const readRetries = 1 << 16
+
+var (
+	// ErrFormat is returned when a file is not a ring file or does not
+	// match the requested capacity and record size.
+	ErrFormat = errors.New("mmapring: bad ring file")
+	// ErrRecordSize is returned by Push for a record of the wrong size.
+	ErrRecordSize = errors.New("mmapring: wrong record size")
+	// ErrLocked is returned by Open when another Ring has the file open.
+	ErrLocked = errors.New("mmapring: file in use by another writer")
+	// ErrBusy is returned by a Reader that could not get a consistent
+	// view, as when the writer died in the middle of a push.
+	ErrBusy = errors.New("mmapring: ring kept changing during read")
+	// ErrClosed is returned when using a closed Ring or Reader.
+	ErrClosed = errors.New("mmapring: ring closed")
+)
+
+// mapping is a ring file mapped in memory.
+type mapping struct {
+	f          *os.File
+	data       []byte
+	capacity   int
+	recordSize int
+}
+
+func (m *mapping) word(off int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&m.data[off]))
+}
+
+func (m *mapping) head() int { return int(atomic.LoadUint64(m.word(offHead))) }
+
+func (m *mapping) generation() uint64 { return atomic.LoadUint64(m.word(offGeneration)) }
+
+// record returns the bytes of the record at buffer position pos.
+func (m *mapping) record(pos int) []byte {
+	off := headerSize + pos*m.recordSize
+	return m.data[off : off+m.recordSize : off+m.recordSize]
+}
+
+// where converts idx, relative to head as in circularbuffer.Get, to a
+// buffer position.
+func (m *mapping) where(head, idx int) int {
+	idx %= m.capacity
+	return (head + idx + m.capacity) % m.capacity
+}
+
+// values appends the records oldest first to a new slice of copies.
+func (m *mapping) values(head int) [][]byte {
+	values := make([][]byte, m.capacity)
+	buf := make([]byte, m.capacity*m.recordSize)
+	for i := range values {
+		values[i] = buf[i*m.recordSize : (i+1)*m.recordSize : (i+1)*m.recordSize]
+		copy(values[i], m.record((head+i)%m.capacity))
+	}
+	return values
+}
+
+func (m *mapping) close() error {
+	if m.data == nil {
+		return ErrClosed
+	}
+	err := munmap(m.data)
+	m.data = nil
+	if cerr := m.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// fileSize is the size of a ring file holding capacity records.
+func fileSize(capacity, recordSize int) int {
+	return headerSize + capacity*recordSize
+}
+
+// checkHeader validates the header in data against the file size.
+func checkHeader(data []byte) (capacity, recordSize int, err error) {
+	if len(data) < headerSize || string(data[:4]) != magic {
+		return 0, 0, ErrFormat
+	}
+	if v := binary.NativeEndian.Uint32(data[offVersion:]); v != version {
+		return 0, 0, fmt.Errorf("%w: version %d", ErrFormat, v)
+	}
+	capacity = int(binary.NativeEndian.Uint64(data[offCapacity:]))
+	recordSize = int(binary.NativeEndian.Uint64(data[offRecordSize:]))
+	head := int(binary.NativeEndian.Uint64(data[offHead:]))
+	if capacity <= 0 || recordSize <= 0 || head >= capacity ||
+		fileSize(capacity, recordSize) != len(data) {
+		return 0, 0, ErrFormat
+	}
+	return capacity, recordSize, nil
+}
+
+// Ring is the writer of a ring file. Only one Ring can have a file open at
+// a time, and a Ring is not safe for concurrent use by several goroutines;
+// other goroutines and processes read the file through a Reader.
+type Ring struct {
+	mapping
+}
+
+// Open opens the ring file at path for writing, creating it with capacity
+// zeroed records of recordSize bytes if it does not exist. An existing file
+// must have the same capacity and record size.
+func Open(path string, capacity, recordSize int) (*Ring, error) {
+	if capacity <= 0 || recordSize <= 0 {
+		return nil, fmt.Errorf("mmapring: invalid capacity %d or record size %d", capacity, recordSize)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	r, err := openRing(f, capacity, recordSize)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func openRing(f *os.File, capacity, recordSize int) (*Ring, error) {
+	if err := lockFile(f); err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fileSize(capacity, recordSize)
+	fresh := fi.Size() == 0
+	if fresh {
+		if err := f.Truncate(int64(size)); err != nil {
+			return nil, err
+		}
+	} else if fi.Size() != int64(size) {
+		return nil, fmt.Errorf("%w: size %d, expected %d", ErrFormat, fi.Size(), size)
+	}
+	data, err := mmap(f, size, true)
+	if err != nil {
+		return nil, err
+	}
+	if fresh {
+		copy(data, magic)
+		binary.NativeEndian.PutUint32(data[offVersion:], version)
+		binary.NativeEndian.PutUint64(data[offCapacity:], uint64(capacity))
+		binary.NativeEndian.PutUint64(data[offRecordSize:], uint64(recordSize))
+	} else if c, rs, err := checkHeader(data); err != nil || c != capacity || rs != recordSize {
+		munmap(data)
+		if err == nil {
+			err = fmt.Errorf("%w: %d records of %d bytes, expected %d of %d", ErrFormat, c, rs, capacity, recordSize)
+		}
+		return nil, err
+	}
+	r := &Ring{mapping{f: f, data: data, capacity: capacity, recordSize: recordSize}}
+	// A writer that died during a push left the generation odd; the
+	// record it was writing may be torn, but the ring is usable again,
+	// and the interrupted push counts in the generation.
+	if g := r.generation(); g&1 != 0 {
+		atomic.StoreUint64(r.word(offGeneration), g+1)
+	}
+	return r, nil
+}
+
+// Len returns the capacity of the ring in records.
+func (r *Ring) Len() int { return r.capacity }
+
+// RecordSize returns the size of each record in bytes.
+func (r *Ring) RecordSize() int { return r.recordSize }
+
+// Generation returns the number of records pushed since the file was
+// created.
+func (r *Ring) Generation() uint64 { return r.generation() / 2 }
+
+// Push stores a copy of rec in place of the oldest record, which it
+// returns. rec must be RecordSize bytes long.
+func (r *Ring) Push(rec []byte) ([]byte, error) {
+	if r.data == nil {
+		return nil, ErrClosed
+	}
+	if len(rec) != r.recordSize {
+		return nil, fmt.Errorf("%w: %d bytes, expected %d", ErrRecordSize, len(rec), r.recordSize)
+	}
+	head := r.head()
+	dst := r.record(head)
+	old := append([]byte(nil), dst...)
+	g := r.generation()
+	atomic.StoreUint64(r.word(offGeneration), g+1)
+	copy(dst, rec)
+	atomic.StoreUint64(r.word(offHead), uint64((head+1)%r.capacity))
+	atomic.StoreUint64(r.word(offGeneration), g+2)
+	return old, nil
+}
+
+// Get returns a copy of the record at idx relative to head: 0 is the
+// oldest record and -1 the newest, as in circularbuffer.Get.
+func (r *Ring) Get(idx int) ([]byte, error) {
+	if r.data == nil {
+		return nil, ErrClosed
+	}
+	return append([]byte(nil), r.record(r.where(r.head(), idx))...), nil
+}
+
+// GetValues returns copies of the records, oldest first.
+func (r *Ring) GetValues() ([][]byte, error) {
+	if r.data == nil {
+		return nil, ErrClosed
+	}
+	return r.values(r.head()), nil
+}
+
+// Sync flushes the mapped file to stable storage.
+func (r *Ring) Sync() error {
+	if r.data == nil {
+		return ErrClosed
+	}
+	return msync(r.data)
+}
+
+// Close flushes and unmaps the file and releases it for other writers.
+func (r *Ring) Close() error {
+	if r.data == nil {
+		return ErrClosed
+	}
+	err := msync(r.data)
+	if cerr := r.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Reader follows a ring file opened read-only, while a Ring in this or
+// another process pushes to it. A Reader is safe for concurrent use by
+// several goroutines, including a Close racing its reads.
+type Reader struct {
+	// mu keeps Close from unmapping data under a read.
+	mu sync.RWMutex
+	mapping
+}
+
+// OpenReader opens the ring file at path read-only, taking capacity and
+// record size from its header.
+func OpenReader(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() < headerSize {
+		f.Close()
+		return nil, ErrFormat
+	}
+	data, err := mmap(f, int(fi.Size()), false)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	capacity, recordSize, err := checkHeader(data)
+	if err != nil {
+		munmap(data)
+		f.Close()
+		return nil, err
+	}
+	return &Reader{mapping: mapping{f: f, data: data, capacity: capacity, recordSize: recordSize}}, nil
+}
+
+// Len returns the capacity of the ring in records.
+func (r *Reader) Len() int { return r.capacity }
+
+// RecordSize returns the size of each record in bytes.
+func (r *Reader) RecordSize() int { return r.recordSize }
+
+// Generation returns the number of records pushed since the file was
+// created. A change in generation means there are new records. It
+// returns 0 once the reader is closed.
+func (r *Reader) Generation() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.data == nil {
+		return 0
+	}
+	return r.generation() / 2
+}
+
+// read calls f with the current head until no push overlaps the call.
+func (r *Reader) read(f func(head int)) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.data == nil {
+		return ErrClosed
+	}
+	for i := 0; i < readRetries; i++ {
+		g := r.generation()
+		if g&1 != 0 {
+			runtime.Gosched()
+			continue
+		}
+		f(r.head())
+		if r.generation() == g {
+			return nil
+		}
+	}
+	return ErrBusy
+}
+
+// Get returns a copy of the record at idx relative to head: 0 is the
+// oldest record and -1 the newest, as in circularbuffer.Get.
+func (r *Reader) Get(idx int) ([]byte, error) {
+	rec := make([]byte, r.recordSize)
+	err := r.read(func(head int) { copy(rec, r.record(r.where(head, idx))) })
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// GetValues returns copies of the records, oldest first.
+func (r *Reader) GetValues() ([][]byte, error) {
+	var values [][]byte
+	err := r.read(func(head int) { values = r.values(head) })
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Close unmaps the file, once reads in progress have finished.
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.close()
+}
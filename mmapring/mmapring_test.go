@@ -0,0 +1,260 @@
+//go:build linux || darwin || freebsd || openbsd || dragonfly
+
+package mmapring
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func rec(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+
+func nums(recs [][]byte) []uint32 {
+	var out []uint32
+	for _, r := range recs {
+		out = append(out, binary.LittleEndian.Uint32(r))
+	}
+	return out
+}
+
+// values returns the records of r as numbers.
+func values(t *testing.T, r *Ring) []uint32 {
+	t.Helper()
+	recs, err := r.GetValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nums(recs)
+}
+
+func open(t *testing.T, path string, capacity int) *Ring {
+	t.Helper()
+	r, err := Open(path, capacity, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestPushGet(t *testing.T) {
+	r := open(t, filepath.Join(t.TempDir(), "ring"), 3)
+	defer r.Close()
+	if got := values(t, r); !slices.Equal(got, []uint32{0, 0, 0}) {
+		t.Fatal("new ring holds", got)
+	}
+	for i := uint32(1); i <= 5; i++ {
+		old, err := r.Push(rec(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := max(int(i)-3, 0); binary.LittleEndian.Uint32(old) != uint32(want) {
+			t.Errorf("Push(%d) dropped %v, expected %d", i, old, want)
+		}
+	}
+	if got := values(t, r); !slices.Equal(got, []uint32{3, 4, 5}) {
+		t.Error("GetValues() =", got)
+	}
+	for idx, want := range map[int]uint32{0: 3, 2: 5, -1: 5, -3: 3, 4: 4, -5: 4} {
+		rec, err := r.Get(idx)
+		if got := binary.LittleEndian.Uint32(rec); got != want || err != nil {
+			t.Errorf("Get(%d) = %d, %v, expected %d", idx, got, err, want)
+		}
+	}
+	if r.Generation() != 5 || r.Len() != 3 || r.RecordSize() != 4 {
+		t.Error("Generation, Len, RecordSize =", r.Generation(), r.Len(), r.RecordSize())
+	}
+	if _, err := r.Push([]byte{1}); !errors.Is(err, ErrRecordSize) {
+		t.Error("Push of short record returned", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring")
+	r := open(t, path, 3)
+	for i := uint32(1); i <= 4; i++ {
+		r.Push(rec(i))
+	}
+	if _, err := Open(path, 3, 4); !errors.Is(err, ErrLocked) {
+		t.Error("second writer got", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != ErrClosed {
+		t.Error("second Close returned", err)
+	}
+	if _, err := r.Get(0); err != ErrClosed {
+		t.Error("Get after Close returned", err)
+	}
+	if _, err := r.GetValues(); err != ErrClosed {
+		t.Error("GetValues after Close returned", err)
+	}
+	if _, err := r.Push(rec(9)); err != ErrClosed {
+		t.Error("Push after Close returned", err)
+	}
+
+	r = open(t, path, 3)
+	if got := values(t, r); !slices.Equal(got, []uint32{2, 3, 4}) || r.Generation() != 4 {
+		t.Error("reopened ring holds", got, "generation", r.Generation())
+	}
+	r.Push(rec(5))
+	r.Close()
+
+	if _, err := Open(path, 4, 4); !errors.Is(err, ErrFormat) {
+		t.Error("capacity mismatch returned", err)
+	}
+	if _, err := Open(path, 3, 8); !errors.Is(err, ErrFormat) {
+		t.Error("record size mismatch returned", err)
+	}
+	bad := filepath.Join(t.TempDir(), "bad")
+	os.WriteFile(bad, make([]byte, fileSize(3, 4)), 0o644)
+	if _, err := Open(bad, 3, 4); !errors.Is(err, ErrFormat) {
+		t.Error("file without header returned", err)
+	}
+	if _, err := OpenReader(bad); !errors.Is(err, ErrFormat) {
+		t.Error("reader of file without header returned", err)
+	}
+}
+
+func TestReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring")
+	r := open(t, path, 4)
+	defer r.Close()
+	rd, err := OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+	if rd.Len() != 4 || rd.RecordSize() != 4 || rd.Generation() != 0 {
+		t.Fatal("Len, RecordSize, Generation =", rd.Len(), rd.RecordSize(), rd.Generation())
+	}
+	for i := uint32(1); i <= 6; i++ {
+		r.Push(rec(i))
+	}
+	values, err := rd.GetValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nums(values); !slices.Equal(got, []uint32{3, 4, 5, 6}) {
+		t.Error("reader GetValues() =", got)
+	}
+	if last, err := rd.Get(-1); err != nil || binary.LittleEndian.Uint32(last) != 6 {
+		t.Error("reader Get(-1) =", last, err)
+	}
+	if rd.Generation() != 6 {
+		t.Error("reader Generation() =", rd.Generation())
+	}
+}
+
+func TestReaderConsistent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring")
+	r, err := Open(path, 8, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	rd, err := OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	// Every record is 64 copies of one byte and each push increments it,
+	// so a torn read shows as a record with mixed bytes or a ring whose
+	// records are not consecutive.
+	var done atomic.Bool
+	go func() {
+		b := make([]byte, 64)
+		for i := 1; i <= 20000; i++ {
+			for j := range b {
+				b[j] = byte(i)
+			}
+			r.Push(b)
+		}
+		done.Store(true)
+	}()
+	for !done.Load() {
+		values, err := rd.GetValues()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range values {
+			for _, c := range v {
+				if c != v[0] {
+					t.Fatal("torn record", v)
+				}
+			}
+			if i > 0 && v[0] != 0 && v[0] != values[i-1][0]+1 {
+				t.Fatal("records out of order", nums(values))
+			}
+		}
+	}
+}
+
+func TestInterruptedPush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring")
+	r := open(t, path, 2)
+	r.Push(rec(1))
+	// Leave the generation odd, as a writer dying during a push would.
+	atomic.AddUint64(r.word(offGeneration), 1)
+	r.Close()
+
+	rd, err := OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+	if _, err := rd.GetValues(); err != ErrBusy {
+		t.Error("read during interrupted push returned", err)
+	}
+	r = open(t, path, 2)
+	defer r.Close()
+	if values, err := rd.GetValues(); err != nil || !slices.Equal(nums(values), []uint32{0, 1}) {
+		t.Error("after reopening the writer, reader got", values, err)
+	}
+	if r.Generation() != 2 {
+		t.Error("Generation() after recovery =", r.Generation())
+	}
+}
+
+func TestReaderCloseWhileReading(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring")
+	r := open(t, path, 8)
+	defer r.Close()
+	for i := uint32(1); i <= 8; i++ {
+		r.Push(rec(i))
+	}
+	rd, err := OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				rd.Generation()
+				if _, err := rd.Get(-1); errors.Is(err, ErrClosed) {
+					return
+				}
+				if _, err := rd.GetValues(); errors.Is(err, ErrClosed) {
+					return
+				}
+			}
+		}()
+	}
+	if err := rd.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if rd.Generation() != 0 {
+		t.Error("closed reader Generation() =", rd.Generation())
+	}
+}
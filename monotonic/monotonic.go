@@ -0,0 +1,187 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package monotonic keeps the maximum or minimum of a sliding window of
+// values in amortized O(1) per value. Values are held in a deque in which
+// each one is better than all those after it: a new value removes from the
+// Last every value it beats, since those can never be the answer again, and
+// values leaving the window are removed from the First.
+//
+// Window slides over the last N values pushed, TimeWindow over the values
+// pushed during the last span of time.
+package monotonic
+
+import (
+	"cmp"
+	"time"
+
+	"github.com/gus-maurizio/structures/deque/v2"
+)
+
+// entry is a value tagged with its position in the stream, a push count
+// for Window and a timestamp in nanoseconds for TimeWindow.
+type entry[T any] struct {
+	pos int64
+	v   T
+}
+
+// ring is the monotonic deque shared by Window and TimeWindow. better
+// reports whether a should be kept in preference to b.
+type ring[T any] struct {
+	q      deque.Deque[entry[T]]
+	better func(a, b T) bool
+}
+
+// push adds v at pos, dropping the values it makes useless.
+func (r *ring[T]) push(pos int64, v T) {
+	for e, ok := r.q.Last(); ok && !r.better(e.v, v); e, ok = r.q.Last() {
+		r.q.PopLast()
+	}
+	r.q.PushLast(entry[T]{pos, v})
+}
+
+// expire drops the values at or before pos.
+func (r *ring[T]) expire(pos int64) {
+	for e, ok := r.q.First(); ok && e.pos <= pos; e, ok = r.q.First() {
+		r.q.PopFirst()
+	}
+}
+
+// value returns the best value left.
+func (r *ring[T]) value() (T, bool) {
+	e, ok := r.q.First()
+	return e.v, ok
+}
+
+// Window tracks the best of the last Size() values pushed.
+type Window[T any] struct {
+	ring[T]
+	size  int
+	count int64
+}
+
+// NewMax returns a Window tracking the maximum of the last size values.
+func NewMax[T cmp.Ordered](size int) *Window[T] {
+	return NewFunc(size, func(a, b T) bool { return a > b })
+}
+
+// NewMin returns a Window tracking the minimum of the last size values.
+func NewMin[T cmp.Ordered](size int) *Window[T] {
+	return NewFunc(size, func(a, b T) bool { return a < b })
+}
+
+// NewFunc returns a Window tracking the best of the last size values,
+// where better(a, b) reports whether a is strictly better than b. A size
+// below 1 is taken as 1.
+func NewFunc[T any](size int, better func(a, b T) bool) *Window[T] {
+	if size < 1 {
+		size = 1
+	}
+	return &Window[T]{ring: ring[T]{better: better}, size: size}
+}
+
+// Size returns the number of values the window covers.
+func (w *Window[T]) Size() int {
+	return w.size
+}
+
+// Len returns the number of values held in the deque, at most Size().
+func (w *Window[T]) Len() int {
+	return w.q.Len()
+}
+
+// Push adds v to the window, sliding out the value pushed Size() pushes
+// before.
+func (w *Window[T]) Push(v T) {
+	w.count++
+	w.push(w.count, v)
+	w.expire(w.count - int64(w.size))
+}
+
+// Value returns the best value in the window, false if nothing was pushed.
+func (w *Window[T]) Value() (T, bool) {
+	return w.value()
+}
+
+// Reset empties the window.
+func (w *Window[T]) Reset() {
+	w.q.Clear()
+	w.count = 0
+}
+
+// TimeWindow tracks the best of the values pushed during the last Span()
+// of time. A value pushed at t is in the window until a Push or Advance
+// with a time after t+Span(). Times must not go backwards.
+type TimeWindow[T any] struct {
+	ring[T]
+	span time.Duration
+	last int64
+}
+
+// NewTimeMax returns a TimeWindow tracking the maximum over span.
+func NewTimeMax[T cmp.Ordered](span time.Duration) *TimeWindow[T] {
+	return NewTimeFunc(span, func(a, b T) bool { return a > b })
+}
+
+// NewTimeMin returns a TimeWindow tracking the minimum over span.
+func NewTimeMin[T cmp.Ordered](span time.Duration) *TimeWindow[T] {
+	return NewTimeFunc(span, func(a, b T) bool { return a < b })
+}
+
+// NewTimeFunc returns a TimeWindow tracking the best value over span,
+// where better(a, b) reports whether a is strictly better than b.
+func NewTimeFunc[T any](span time.Duration, better func(a, b T) bool) *TimeWindow[T] {
+	return &TimeWindow[T]{ring: ring[T]{better: better}, span: span}
+}
+
+// Span returns the length of time the window covers.
+func (w *TimeWindow[T]) Span() time.Duration {
+	return w.span
+}
+
+// Len returns the number of values held in the deque.
+func (w *TimeWindow[T]) Len() int {
+	return w.q.Len()
+}
+
+// Push adds v with timestamp t and slides the window to end at t.
+func (w *TimeWindow[T]) Push(t time.Time, v T) {
+	w.Advance(t)
+	w.push(t.UnixNano(), v)
+}
+
+// Advance slides the window to end at now, dropping the values older
+// than now-Span().
+func (w *TimeWindow[T]) Advance(now time.Time) {
+	w.last = now.UnixNano()
+	w.expire(w.last - int64(w.span) - 1)
+}
+
+// Value returns the best value in the window as of the last Push or
+// Advance, false if the window is empty.
+func (w *TimeWindow[T]) Value() (T, bool) {
+	return w.value()
+}
+
+// Reset empties the window.
+func (w *TimeWindow[T]) Reset() {
+	w.q.Clear()
+	w.last = 0
+}
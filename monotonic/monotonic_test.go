@@ -0,0 +1,118 @@
+package monotonic
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWindowAgainstNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{1, 3, 16, 100} {
+		hi, lo := NewMax[int](size), NewMin[int](size)
+		var all []int
+		for i := 0; i < 3000; i++ {
+			v := r.Intn(1000)
+			if i%500 < 100 {
+				// Stretches of rising values keep the deque short,
+				// falling ones keep it long.
+				v = i
+			} else if i%500 < 200 {
+				v = -i
+			}
+			hi.Push(v)
+			lo.Push(v)
+			all = append(all, v)
+			last := all[max(0, len(all)-size):]
+			if m, _ := hi.Value(); m != slices.Max(last) {
+				t.Fatalf("size %d step %d: max %d, expected %d", size, i, m, slices.Max(last))
+			}
+			if m, _ := lo.Value(); m != slices.Min(last) {
+				t.Fatalf("size %d step %d: min %d, expected %d", size, i, m, slices.Min(last))
+			}
+			if hi.Len() > size || lo.Len() > size {
+				t.Fatalf("size %d step %d: deque holds %d and %d values", size, i, hi.Len(), lo.Len())
+			}
+		}
+	}
+}
+
+func TestWindowEqualValues(t *testing.T) {
+	w := NewMax[int](3)
+	for _, v := range []int{5, 5, 5, 1, 1} {
+		w.Push(v)
+	}
+	// The last 5 was pushed 3 pushes ago and is still in the window.
+	if m, _ := w.Value(); m != 5 {
+		t.Error("Value() =", m, "expected 5")
+	}
+	w.Push(1)
+	if m, _ := w.Value(); m != 1 {
+		t.Error("Value() =", m, "expected 1")
+	}
+}
+
+func TestWindowFunc(t *testing.T) {
+	type sample struct {
+		name    string
+		latency time.Duration
+	}
+	w := NewFunc(2, func(a, b sample) bool { return a.latency > b.latency })
+	if _, ok := w.Value(); ok {
+		t.Error("Value() on empty window returned ok")
+	}
+	w.Push(sample{"a", 3 * time.Millisecond})
+	w.Push(sample{"b", time.Millisecond})
+	w.Push(sample{"c", 2 * time.Millisecond})
+	if s, _ := w.Value(); s.name != "c" {
+		t.Error("Value() =", s, "expected c")
+	}
+	w.Reset()
+	if _, ok := w.Value(); ok {
+		t.Error("Value() after Reset() returned ok")
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	start := time.Unix(1000, 0)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	hi := NewTimeMax[float64](5 * time.Second)
+	lo := NewTimeMin[float64](5 * time.Second)
+
+	for s, v := range []float64{3, 9, 4, 1, 2, 6, 5} {
+		hi.Push(at(s), v)
+		lo.Push(at(s), v)
+	}
+	// At 6s the window covers [1s, 6s]: 9 4 1 2 6 5.
+	if m, _ := hi.Value(); m != 9 {
+		t.Error("max =", m, "expected 9")
+	}
+	if m, _ := lo.Value(); m != 1 {
+		t.Error("min =", m, "expected 1")
+	}
+
+	// At 7s the 9 pushed at 1s is out: 4 1 2 6 5.
+	hi.Advance(at(7))
+	if m, _ := hi.Value(); m != 6 {
+		t.Error("max after 7s =", m, "expected 6")
+	}
+	// At 10s only 6 and 5 are left.
+	lo.Advance(at(10))
+	if m, _ := lo.Value(); m != 5 {
+		t.Error("min after 10s =", m, "expected 5")
+	}
+	// Everything ages out after a long gap.
+	hi.Advance(at(60))
+	if _, ok := hi.Value(); ok {
+		t.Error("max after 60s returned ok")
+	}
+}
+
+func BenchmarkWindow(b *testing.B) {
+	w := NewMax[int](1024)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < b.N; i++ {
+		w.Push(r.Int())
+	}
+}
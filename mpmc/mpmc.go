@@ -0,0 +1,198 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package mpmc provides a bounded lock-free queue for any number of
+// producer and consumer goroutines. Each slot of the power of 2 ring
+// carries a sequence number that tells producers and consumers whether
+// the slot is free for the current lap, so a position is claimed with a
+// single compare-and-swap on the head or tail index.
+package mpmc
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// cacheLine separates head and tail, which producers and consumers update
+// from different cores, by one 64-byte cache line.
+const cacheLine = 64
+
+// maxBackoff bounds the sleep between retries of Enqueue and Dequeue.
+const maxBackoff = time.Millisecond
+
+type slot[T any] struct {
+	seq atomic.Uint64
+	val T
+}
+
+// Queue is a bounded multi-producer/multi-consumer FIFO queue.
+type Queue[T any] struct {
+	_ [cacheLine]byte
+	// head is the next position to dequeue.
+	head atomic.Uint64
+	_    [cacheLine - 8]byte
+	// tail is the next position to enqueue.
+	tail  atomic.Uint64
+	_     [cacheLine - 8]byte
+	mask  uint64
+	slots []slot[T]
+}
+
+// New returns an empty queue holding at least capacity elements. The
+// capacity is rounded up to a power of 2, and is at least 2.
+func New[T any](capacity int) *Queue[T] {
+	size := 2
+	for size < capacity {
+		size <<= 1
+	}
+	q := &Queue[T]{mask: uint64(size - 1), slots: make([]slot[T], size)}
+	for i := range q.slots {
+		q.slots[i].seq.Store(uint64(i))
+	}
+	return q
+}
+
+// Cap returns the number of elements the queue holds when full.
+func (q *Queue[T]) Cap() int {
+	return len(q.slots)
+}
+
+// Len returns the number of elements in the queue. With producers and
+// consumers running it is only a snapshot.
+func (q *Queue[T]) Len() int {
+	head := q.head.Load()
+	tail := q.tail.Load()
+	if tail < head {
+		return 0
+	}
+	return int(tail - head)
+}
+
+// TryEnqueue appends v to the queue. It returns false, without waiting,
+// if the queue is full.
+func (q *Queue[T]) TryEnqueue(v T) bool {
+	pos := q.tail.Load()
+	for {
+		s := &q.slots[pos&q.mask] // bitwise modulus
+		seq := s.seq.Load()
+		switch dif := int64(seq - pos); {
+		case dif == 0:
+			// The slot is free for this lap; claim the position.
+			if q.tail.CompareAndSwap(pos, pos+1) {
+				s.val = v
+				s.seq.Store(pos + 1)
+				return true
+			}
+			pos = q.tail.Load()
+		case dif < 0:
+			// The slot still holds the element from the previous lap.
+			return false
+		default:
+			// Another producer claimed pos first.
+			pos = q.tail.Load()
+		}
+	}
+}
+
+// TryDequeue removes and returns the oldest element of the queue. It
+// returns false, without waiting, if the queue is empty.
+func (q *Queue[T]) TryDequeue() (T, bool) {
+	pos := q.head.Load()
+	for {
+		s := &q.slots[pos&q.mask] // bitwise modulus
+		seq := s.seq.Load()
+		switch dif := int64(seq - (pos + 1)); {
+		case dif == 0:
+			// The slot holds the element for this lap; claim it.
+			if q.head.CompareAndSwap(pos, pos+1) {
+				v := s.val
+				var zero T
+				s.val = zero
+				// Free the slot for the producer of the next lap.
+				s.seq.Store(pos + q.mask + 1)
+				return v, true
+			}
+			pos = q.head.Load()
+		case dif < 0:
+			// No producer has filled the slot yet.
+			var zero T
+			return zero, false
+		default:
+			// Another consumer claimed pos first.
+			pos = q.head.Load()
+		}
+	}
+}
+
+// Enqueue appends v to the queue, retrying with a growing backoff while
+// the queue is full. It returns ctx.Err() if ctx is done first.
+func (q *Queue[T]) Enqueue(ctx context.Context, v T) error {
+	for try := 0; ; try++ {
+		if q.TryEnqueue(v) {
+			return nil
+		}
+		if err := backoff(ctx, try); err != nil {
+			return err
+		}
+	}
+}
+
+// Dequeue removes and returns the oldest element of the queue, retrying
+// with a growing backoff while the queue is empty. It returns ctx.Err()
+// if ctx is done first.
+func (q *Queue[T]) Dequeue(ctx context.Context) (T, error) {
+	for try := 0; ; try++ {
+		if v, ok := q.TryDequeue(); ok {
+			return v, nil
+		}
+		if err := backoff(ctx, try); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+}
+
+// backoff yields the processor for the first retries and then sleeps,
+// doubling the sleep up to maxBackoff, unless ctx is done.
+func backoff(ctx context.Context, try int) error {
+	if try < 16 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		runtime.Gosched()
+		return nil
+	}
+	d := time.Microsecond << min(try-16, 10)
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,188 @@
+package mpmc
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCapacity(t *testing.T) {
+	for capacity, want := range map[int]int{0: 2, 1: 2, 3: 4, 64: 64, 100: 128} {
+		if got := New[int](capacity).Cap(); got != want {
+			t.Error("New", capacity, "has Cap", got, "expected", want)
+		}
+	}
+}
+
+func TestFullEmpty(t *testing.T) {
+	q := New[int](4)
+	if _, ok := q.TryDequeue(); ok {
+		t.Error("TryDequeue succeeded on empty queue")
+	}
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 4; i++ {
+			if !q.TryEnqueue(i) {
+				t.Fatal("TryEnqueue", i, "failed")
+			}
+		}
+		if q.TryEnqueue(4) {
+			t.Error("TryEnqueue succeeded on full queue")
+		}
+		if q.Len() != 4 {
+			t.Error("q.Len() =", q.Len(), "expected 4")
+		}
+		for i := 0; i < 4; i++ {
+			if v, ok := q.TryDequeue(); !ok || v != i {
+				t.Error("TryDequeue() =", v, ok, "expected", i)
+			}
+		}
+	}
+}
+
+func TestBlockingContext(t *testing.T) {
+	q := New[int](2)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Dequeue(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Dequeue() on empty queue returned", err)
+	}
+	q.TryEnqueue(1)
+	q.TryEnqueue(2)
+	if err := q.Enqueue(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Enqueue() on full queue returned", err)
+	}
+}
+
+// stress runs producers and consumers through a small queue and checks
+// that every element is dequeued exactly once and that each producer's
+// elements come out in the order they went in.
+func stress(t *testing.T, capacity, producers, consumers, items int, blocking bool) {
+	q := New[int](capacity)
+	ctx := context.Background()
+	total := producers * items
+	seen := make([]int32, total)
+	var pwg, cwg sync.WaitGroup
+	var taken sync.WaitGroup
+	taken.Add(total)
+
+	for p := 0; p < producers; p++ {
+		pwg.Add(1)
+		go func(p int) {
+			defer pwg.Done()
+			for i := 0; i < items; i++ {
+				v := p*items + i
+				if blocking {
+					q.Enqueue(ctx, v)
+					continue
+				}
+				for !q.TryEnqueue(v) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+	done := make(chan struct{})
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			last := make([]int, producers)
+			for i := range last {
+				last[i] = -1
+			}
+			for {
+				var v int
+				if blocking {
+					cctx, cancel := context.WithCancel(ctx)
+					go func() {
+						select {
+						case <-done:
+						case <-cctx.Done():
+						}
+						cancel()
+					}()
+					var err error
+					v, err = q.Dequeue(cctx)
+					cancel()
+					if err != nil {
+						return
+					}
+				} else {
+					var ok bool
+					if v, ok = q.TryDequeue(); !ok {
+						select {
+						case <-done:
+							return
+						default:
+							runtime.Gosched()
+							continue
+						}
+					}
+				}
+				p, i := v/items, v%items
+				if i <= last[p] {
+					t.Errorf("producer %d element %d dequeued after %d", p, i, last[p])
+				}
+				last[p] = i
+				seen[v]++
+				taken.Done()
+			}
+		}()
+	}
+	pwg.Wait()
+	taken.Wait()
+	close(done)
+	cwg.Wait()
+
+	for v, n := range seen {
+		if n != 1 {
+			t.Fatalf("element %d dequeued %d times", v, n)
+		}
+	}
+	if q.Len() != 0 {
+		t.Error("q.Len() =", q.Len(), "expected 0")
+	}
+}
+
+func TestStressSPSC(t *testing.T) {
+	stress(t, 4, 1, 1, 20000, false)
+}
+
+func TestStressFanIn(t *testing.T) {
+	stress(t, 8, 8, 1, 5000, false)
+}
+
+func TestStressFanOut(t *testing.T) {
+	stress(t, 8, 1, 8, 20000, false)
+}
+
+func TestStressMPMC(t *testing.T) {
+	stress(t, 16, 8, 8, 5000, false)
+}
+
+func TestStressBlocking(t *testing.T) {
+	stress(t, 2, 4, 4, 2000, true)
+}
+
+func BenchmarkMPMC(b *testing.B) {
+	q := New[int](1024)
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if i%2 == 0 {
+				for !q.TryEnqueue(i) {
+					runtime.Gosched()
+				}
+			} else {
+				for {
+					if _, ok := q.TryDequeue(); ok {
+						break
+					}
+					runtime.Gosched()
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,229 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package pqueue provides priority queues: Heap, a d-ary heap whose
+// elements can be updated or removed through the handle returned by Push,
+// and TopK, which keeps the best k of the values pushed to it.
+package pqueue
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Item is an element of a Heap and the handle to it. After changing Value
+// call Heap.Fix to restore the order.
+type Item[T any] struct {
+	Value T
+	index int    // position in the heap, -1 once removed
+	seq   uint64 // insertion order, for stable heaps
+}
+
+// Heap is a priority queue ordered by a less function: Pop returns an
+// element that no other is less than. The zero Heap is not usable; call
+// New, NewMin or NewMax.
+type Heap[T any] struct {
+	items  []*Item[T]
+	less   func(a, b T) bool
+	arity  int
+	stable bool
+	seq    uint64
+}
+
+// Option configures a Heap when passed to New.
+type Option[T any] func(*Heap[T])
+
+// WithArity makes the heap d-ary: each node has d children instead of 2.
+// Wider heaps are shallower, which makes Push and Fix cheaper and Pop
+// dearer. A d below 2 is taken as 2.
+func WithArity[T any](d int) Option[T] {
+	return func(h *Heap[T]) { h.arity = max(d, 2) }
+}
+
+// WithStable breaks ties between equal elements by insertion order, so
+// that equal elements are popped first in, first out. Fix keeps the
+// original insertion order of an element.
+func WithStable[T any]() Option[T] {
+	return func(h *Heap[T]) { h.stable = true }
+}
+
+// New returns an empty heap ordered by less.
+func New[T any](less func(a, b T) bool, opts ...Option[T]) *Heap[T] {
+	h := &Heap[T]{less: less, arity: 2}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// NewMin returns an empty heap that pops its smallest element first.
+func NewMin[T cmp.Ordered](opts ...Option[T]) *Heap[T] {
+	return New(cmp.Less[T], opts...)
+}
+
+// NewMax returns an empty heap that pops its largest element first.
+func NewMax[T cmp.Ordered](opts ...Option[T]) *Heap[T] {
+	return New(func(a, b T) bool { return cmp.Less(b, a) }, opts...)
+}
+
+// Len returns the number of elements in the heap.
+func (h *Heap[T]) Len() int { return len(h.items) }
+
+// Push adds v to the heap and returns its handle.
+func (h *Heap[T]) Push(v T) *Item[T] {
+	it := &Item[T]{Value: v, index: len(h.items), seq: h.seq}
+	h.seq++
+	h.items = append(h.items, it)
+	h.up(it.index)
+	return it
+}
+
+// Peek returns the first element without removing it.
+func (h *Heap[T]) Peek() (T, bool) {
+	if len(h.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return h.items[0].Value, true
+}
+
+// Pop removes and returns the first element.
+func (h *Heap[T]) Pop() (T, bool) {
+	if len(h.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return h.removeAt(0).Value, true
+}
+
+// Fix restores the order after the Value of it has changed. It has no
+// effect if it is not in the heap.
+func (h *Heap[T]) Fix(it *Item[T]) {
+	if !h.contains(it) {
+		return
+	}
+	if !h.down(it.index) {
+		h.up(it.index)
+	}
+}
+
+// Remove removes it from the heap and reports whether it was there.
+func (h *Heap[T]) Remove(it *Item[T]) bool {
+	if !h.contains(it) {
+		return false
+	}
+	h.removeAt(it.index)
+	return true
+}
+
+// Contains reports whether it is in the heap.
+func (h *Heap[T]) Contains(it *Item[T]) bool { return h.contains(it) }
+
+// Clear removes every element.
+func (h *Heap[T]) Clear() {
+	for _, it := range h.items {
+		it.index = -1
+	}
+	clear(h.items)
+	h.items = h.items[:0]
+}
+
+// All returns an iterator over the elements in heap order, which is not
+// sorted order. The heap must not be changed during the iteration.
+func (h *Heap[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, it := range h.items {
+			if !yield(it.Value) {
+				return
+			}
+		}
+	}
+}
+
+func (h *Heap[T]) contains(it *Item[T]) bool {
+	return it != nil && it.index >= 0 && it.index < len(h.items) && h.items[it.index] == it
+}
+
+// before reports whether the element at i goes before the one at j.
+func (h *Heap[T]) before(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.Value, b.Value) {
+		return true
+	}
+	return h.stable && a.seq < b.seq && !h.less(b.Value, a.Value)
+}
+
+func (h *Heap[T]) swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *Heap[T]) up(i int) {
+	for i > 0 {
+		p := (i - 1) / h.arity
+		if !h.before(i, p) {
+			break
+		}
+		h.swap(i, p)
+		i = p
+	}
+}
+
+// down moves the element at i towards the leaves and reports whether it
+// moved.
+func (h *Heap[T]) down(i int) bool {
+	start := i
+	n := len(h.items)
+	for {
+		first := h.arity*i + 1
+		if first >= n {
+			break
+		}
+		best := first
+		for c := first + 1; c < min(first+h.arity, n); c++ {
+			if h.before(c, best) {
+				best = c
+			}
+		}
+		if !h.before(best, i) {
+			break
+		}
+		h.swap(i, best)
+		i = best
+	}
+	return i > start
+}
+
+// removeAt removes and returns the item at i.
+func (h *Heap[T]) removeAt(i int) *Item[T] {
+	n := len(h.items) - 1
+	if i != n {
+		h.swap(i, n)
+	}
+	it := h.items[n]
+	h.items[n] = nil
+	h.items = h.items[:n]
+	if i != n && !h.down(i) {
+		h.up(i)
+	}
+	it.index = -1
+	return it
+}
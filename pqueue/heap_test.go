@@ -0,0 +1,117 @@
+package pqueue
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func drain[T any](h *Heap[T]) []T {
+	var out []T
+	for {
+		v, ok := h.Pop()
+		if !ok {
+			return out
+		}
+		out = append(out, v)
+	}
+}
+
+func TestHeapSort(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, d := range []int{2, 3, 4, 8} {
+		h := NewMin(WithArity[int](d))
+		var want []int
+		for i := 0; i < 500; i++ {
+			v := r.IntN(100)
+			h.Push(v)
+			want = append(want, v)
+		}
+		slices.Sort(want)
+		if v, ok := h.Peek(); !ok || v != want[0] {
+			t.Errorf("arity %d: Peek() = %d, %v", d, v, ok)
+		}
+		if got := drain(h); !slices.Equal(got, want) {
+			t.Errorf("arity %d: popped out of order", d)
+		}
+	}
+	h := NewMax[string]()
+	for _, s := range []string{"b", "d", "a", "c"} {
+		h.Push(s)
+	}
+	if got := drain(h); !slices.Equal(got, []string{"d", "c", "b", "a"}) {
+		t.Error("NewMax popped", got)
+	}
+	if _, ok := h.Pop(); ok {
+		t.Error("Pop on empty heap succeeded")
+	}
+}
+
+type task struct {
+	name     string
+	priority int
+}
+
+func TestHeapStable(t *testing.T) {
+	byPriority := func(a, b task) bool { return a.priority < b.priority }
+	h := New(byPriority, WithStable[task]())
+	for i, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		h.Push(task{name, i % 2})
+	}
+	var names string
+	for _, v := range drain(h) {
+		names += v.name
+	}
+	if names != "acegbdfh" {
+		t.Error("stable heap popped", names)
+	}
+}
+
+func TestHeapFixRemove(t *testing.T) {
+	h := NewMin(WithArity[int](3))
+	items := make([]*Item[int], 20)
+	for i := range items {
+		items[i] = h.Push(i * 10)
+	}
+	items[15].Value = -1
+	h.Fix(items[15])
+	items[0].Value = 1000
+	h.Fix(items[0])
+	if v, _ := h.Peek(); v != -1 {
+		t.Error("Peek() after Fix =", v)
+	}
+	if !h.Remove(items[7]) || h.Remove(items[7]) || h.Contains(items[7]) {
+		t.Error("Remove of item 7 misreported")
+	}
+	if h.Remove(nil) {
+		t.Error("Remove(nil) succeeded")
+	}
+	got := drain(h)
+	want := []int{-1, 10, 20, 30, 40, 50, 60, 80, 90, 100, 110, 120, 130, 140, 160, 170, 180, 190, 1000}
+	if !slices.Equal(got, want) {
+		t.Error("popped", got)
+	}
+	// Handles of popped elements are inert.
+	items[3].Value = -5
+	h.Fix(items[3])
+	if h.Len() != 0 {
+		t.Error("Fix of popped item changed the heap")
+	}
+}
+
+func TestHeapClear(t *testing.T) {
+	h := NewMin[int]()
+	a := h.Push(1)
+	h.Push(2)
+	h.Clear()
+	if h.Len() != 0 || h.Contains(a) {
+		t.Error("Clear left", h.Len(), "elements")
+	}
+	h.Push(3)
+	if h.Contains(a) || h.Remove(a) {
+		t.Error("stale handle found after Clear")
+	}
+	if got := slices.Collect(h.All()); !slices.Equal(got, []int{3}) {
+		t.Error("All() =", got)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package pqueue
+
+import "slices"
+
+// TopK keeps the k best values pushed to it, best meaning first in the
+// order of its less function. Its heap is kept in reverse, so the worst
+// of the kept values is at the root, ready to be displaced.
+type TopK[T any] struct {
+	k    int
+	less func(a, b T) bool
+	h    *Heap[T]
+}
+
+// NewTopK returns an empty TopK keeping the k values that go first by
+// less. A k below 1 is taken as 1. The options configure the underlying
+// heap.
+func NewTopK[T any](k int, less func(a, b T) bool, opts ...Option[T]) *TopK[T] {
+	k = max(k, 1)
+	worse := func(a, b T) bool { return less(b, a) }
+	return &TopK[T]{k: k, less: less, h: New(worse, opts...)}
+}
+
+// Len returns the number of values kept, at most K.
+func (t *TopK[T]) Len() int { return t.h.Len() }
+
+// K returns the number of values kept when full.
+func (t *TopK[T]) K() int { return t.k }
+
+// Push offers v. Once K values are kept, either v goes in and the worst
+// kept value is displaced, or v is not better than any of them and is
+// displaced itself; like circularbuffer.Push, the displaced value is
+// returned, with true if there was one.
+func (t *TopK[T]) Push(v T) (T, bool) {
+	if t.h.Len() < t.k {
+		t.h.Push(v)
+		var zero T
+		return zero, false
+	}
+	root := t.h.items[0]
+	if !t.less(v, root.Value) {
+		return v, true
+	}
+	old := root.Value
+	root.Value = v
+	root.seq = t.h.seq
+	t.h.seq++
+	t.h.down(0)
+	return old, true
+}
+
+// Worst returns the kept value that would be displaced next.
+func (t *TopK[T]) Worst() (T, bool) { return t.h.Peek() }
+
+// Values returns the kept values, best first.
+func (t *TopK[T]) Values() []T {
+	values := slices.Collect(t.h.All())
+	slices.SortStableFunc(values, func(a, b T) int {
+		switch {
+		case t.less(a, b):
+			return -1
+		case t.less(b, a):
+			return 1
+		}
+		return 0
+	})
+	return values
+}
+
+// Reset drops every kept value.
+func (t *TopK[T]) Reset() { t.h.Clear() }
@@ -0,0 +1,50 @@
+package pqueue
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	// Keep the 3 largest values.
+	top := NewTopK(3, func(a, b int) bool { return a > b })
+	pushes := []struct {
+		v, displaced int
+		ok           bool
+	}{
+		{5, 0, false}, {1, 0, false}, {9, 0, false},
+		{7, 1, true}, {2, 2, true}, {5, 5, true}, {10, 5, true},
+	}
+	for _, p := range pushes {
+		if d, ok := top.Push(p.v); d != p.displaced || ok != p.ok {
+			t.Errorf("Push(%d) = %d, %v, expected %d, %v", p.v, d, ok, p.displaced, p.ok)
+		}
+	}
+	if got := top.Values(); !slices.Equal(got, []int{10, 9, 7}) {
+		t.Error("Values() =", got)
+	}
+	if w, ok := top.Worst(); !ok || w != 7 || top.Len() != 3 || top.K() != 3 {
+		t.Error("Worst() =", w, ok)
+	}
+	top.Reset()
+	if top.Len() != 0 {
+		t.Error("Len() after Reset =", top.Len())
+	}
+}
+
+func TestTopKRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	top := NewTopK(10, cmp.Less[int], WithArity[int](4))
+	var all []int
+	for i := 0; i < 1000; i++ {
+		v := r.IntN(10000)
+		all = append(all, v)
+		top.Push(v)
+	}
+	slices.Sort(all)
+	if got := top.Values(); !slices.Equal(got, all[:10]) {
+		t.Error("Values() =", got, "expected", all[:10])
+	}
+}
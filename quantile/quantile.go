@@ -0,0 +1,130 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package quantile answers quantile queries, such as p50, p95 and p99,
+// over the last N samples held in a circular buffer. Next to the ring it
+// keeps an order statistics structure: each Push inserts the new sample
+// and deletes the one the circular buffer drops, so the window is never
+// sorted at query time.
+//
+// The default estimator is exact, a treap counting the samples below each
+// node, with O(log n) Push and Quantile. For very large windows the sketch
+// estimator keeps only a count per logarithmic bucket instead of a node per
+// sample, answering within a chosen relative error; Quantile walks the b
+// buckets in use, O(b), and b grows with the range of the samples rather
+// than their number. Either way the ring holds all N samples, as it must
+// to know which sample each Push evicts.
+package quantile
+
+import (
+	"math"
+
+	"github.com/gus-maurizio/structures/circularbuffer"
+)
+
+// estimator is an order statistics structure that supports removal.
+type estimator interface {
+	insert(x float64)
+	remove(x float64)
+	// kth returns the k-th smallest sample, 1 <= k <= number of samples.
+	kth(k int) float64
+	reset()
+}
+
+// Window holds the last Size() samples and answers quantiles over them.
+type Window struct {
+	buf *circularbuffer.CircularBuffer[float64]
+	n   int
+	est estimator
+}
+
+// Option configures a Window when passed to New.
+type Option func(*Window)
+
+// WithSketch makes the window estimate quantiles with a logarithmic
+// bucket sketch, so that an answer x' for a true quantile x satisfies
+// |x'-x| <= accuracy*|x|. Accuracy must be between 0 and 1, e.g. 0.01.
+// Samples closer to 0 than 1e-9 are counted as 0.
+func WithSketch(accuracy float64) Option {
+	return func(w *Window) { w.est = newSketch(accuracy) }
+}
+
+// New returns an empty window of size samples with an exact estimator,
+// unless changed by opts. A size below 1 is taken as 1.
+func New(size int, opts ...Option) *Window {
+	if size < 1 {
+		size = 1
+	}
+	w := &Window{buf: circularbuffer.New(size, 0.0), est: newTreap()}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Size returns the number of samples the window holds when full.
+func (w *Window) Size() int {
+	return w.buf.Len
+}
+
+// Len returns the number of samples in the window.
+func (w *Window) Len() int {
+	return w.n
+}
+
+// Push adds x to the window. Once the window is full it drops the oldest
+// sample, which is returned with true. NaN samples are not supported.
+func (w *Window) Push(x float64) (float64, bool) {
+	old := w.buf.Push(x)
+	evicted := w.n == w.buf.Len
+	if evicted {
+		w.est.remove(old)
+	} else {
+		w.n++
+	}
+	w.est.insert(x)
+	if !evicted {
+		return 0, false
+	}
+	return old, true
+}
+
+// Quantile returns the sample of rank ceil(q*Len()), the smallest sample
+// for q <= 0 and the largest for q >= 1, or false if the window is empty.
+func (w *Window) Quantile(q float64) (float64, bool) {
+	if w.n == 0 {
+		return 0, false
+	}
+	k := int(math.Ceil(q * float64(w.n)))
+	k = min(max(k, 1), w.n)
+	return w.est.kth(k), true
+}
+
+// Median returns Quantile(0.5).
+func (w *Window) Median() (float64, bool) {
+	return w.Quantile(0.5)
+}
+
+// Reset empties the window.
+func (w *Window) Reset() {
+	w.buf.Init(0)
+	w.n = 0
+	w.est.reset()
+}
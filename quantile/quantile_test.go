@@ -0,0 +1,114 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// rank returns the nearest-rank quantile of values the slow way.
+func rank(values []float64, q float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	k := int(math.Ceil(q * float64(len(sorted))))
+	k = min(max(k, 1), len(sorted))
+	return sorted[k-1]
+}
+
+func TestEmpty(t *testing.T) {
+	for _, w := range []*Window{New(10), New(10, WithSketch(0.01))} {
+		if _, ok := w.Quantile(0.5); ok {
+			t.Error("Quantile() on empty window returned ok")
+		}
+	}
+}
+
+func TestSmall(t *testing.T) {
+	w := New(5)
+	for _, x := range []float64{5, 1, 4, 2, 3} {
+		w.Push(x)
+	}
+	for q, want := range map[float64]float64{0: 1, 0.2: 1, 0.5: 3, 0.95: 5, 1: 5} {
+		if got, _ := w.Quantile(q); got != want {
+			t.Errorf("Quantile(%v) = %v, expected %v", q, got, want)
+		}
+	}
+	// Push drops 5, the oldest.
+	if old, evicted := w.Push(0); !evicted || old != 5 {
+		t.Error("Push(0) =", old, evicted)
+	}
+	if got, _ := w.Quantile(1); got != 4 {
+		t.Error("max after eviction =", got)
+	}
+}
+
+func TestExactAgainstSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{1, 10, 257} {
+		w := New(size)
+		var all []float64
+		for i := 0; i < 3000; i++ {
+			// Round to get many duplicates.
+			x := math.Round(r.ExpFloat64() * 20)
+			w.Push(x)
+			all = append(all, x)
+			last := all[max(0, len(all)-size):]
+			for _, q := range []float64{0, 0.5, 0.95, 0.99, 1} {
+				if got, _ := w.Quantile(q); got != rank(last, q) {
+					t.Fatalf("size %d step %d: Quantile(%v) = %v, expected %v",
+						size, i, q, got, rank(last, q))
+				}
+			}
+		}
+		w.Reset()
+		if w.Len() != 0 {
+			t.Error("Reset() left samples")
+		}
+	}
+}
+
+func TestSketchAccuracy(t *testing.T) {
+	const accuracy = 0.02
+	r := rand.New(rand.NewSource(2))
+	w := New(5000, WithSketch(accuracy))
+	var all []float64
+	for i := 0; i < 20000; i++ {
+		x := r.NormFloat64()*50 + 10
+		if i%100 == 0 {
+			x = 0
+		}
+		w.Push(x)
+		all = append(all, x)
+	}
+	last := all[len(all)-5000:]
+	for _, q := range []float64{0.01, 0.25, 0.5, 0.95, 0.99} {
+		got, _ := w.Quantile(q)
+		want := rank(last, q)
+		if math.Abs(got-want) > accuracy*math.Abs(want)+1e-9 {
+			t.Errorf("Quantile(%v) = %v, expected %v within %v", q, got, want, accuracy)
+		}
+	}
+}
+
+func BenchmarkExact(b *testing.B) {
+	w := New(100000)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < b.N; i++ {
+		w.Push(r.ExpFloat64())
+		if i%100 == 0 {
+			w.Quantile(0.99)
+		}
+	}
+}
+
+func BenchmarkSketch(b *testing.B) {
+	w := New(100000, WithSketch(0.01))
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < b.N; i++ {
+		w.Push(r.ExpFloat64())
+		if i%100 == 0 {
+			w.Quantile(0.99)
+		}
+	}
+}
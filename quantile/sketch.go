@@ -0,0 +1,143 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package quantile
+
+import (
+	"math"
+	"slices"
+)
+
+// minIndexable is the smallest magnitude given its own bucket; samples
+// closer to 0 are counted as 0.
+const minIndexable = 1e-9
+
+// sketch is the approximate estimator. A positive sample x is counted in
+// bucket ceil(log_gamma(x)), whose samples all lie within the relative
+// accuracy of the bucket's representative value; negative samples are
+// counted the same way by magnitude.
+type sketch struct {
+	gamma    float64
+	logGamma float64
+	pos, neg buckets
+	zeros    int
+}
+
+// buckets holds the non-empty buckets of one sign in index order, so that
+// kth walks them without sorting. Adding or dropping a bucket shifts the
+// ones after it, which is cheap as there are few of them.
+type buckets struct {
+	index []int
+	count []int
+}
+
+func (b *buckets) add(i int) {
+	j, found := slices.BinarySearch(b.index, i)
+	if found {
+		b.count[j]++
+		return
+	}
+	b.index = slices.Insert(b.index, j, i)
+	b.count = slices.Insert(b.count, j, 1)
+}
+
+func (b *buckets) remove(i int) {
+	j, found := slices.BinarySearch(b.index, i)
+	if !found {
+		return
+	}
+	if b.count[j] > 1 {
+		b.count[j]--
+		return
+	}
+	b.index = slices.Delete(b.index, j, j+1)
+	b.count = slices.Delete(b.count, j, j+1)
+}
+
+func (b *buckets) reset() {
+	b.index = b.index[:0]
+	b.count = b.count[:0]
+}
+
+func newSketch(accuracy float64) *sketch {
+	if accuracy <= 0 || accuracy >= 1 {
+		accuracy = 0.01
+	}
+	gamma := (1 + accuracy) / (1 - accuracy)
+	return &sketch{gamma: gamma, logGamma: math.Log(gamma)}
+}
+
+func (s *sketch) index(x float64) int {
+	return int(math.Ceil(math.Log(x) / s.logGamma))
+}
+
+// value returns the representative value of bucket i.
+func (s *sketch) value(i int) float64 {
+	return 2 * math.Pow(s.gamma, float64(i)) / (s.gamma + 1)
+}
+
+func (s *sketch) insert(x float64) {
+	switch {
+	case x >= minIndexable:
+		s.pos.add(s.index(x))
+	case x <= -minIndexable:
+		s.neg.add(s.index(-x))
+	default:
+		s.zeros++
+	}
+}
+
+func (s *sketch) remove(x float64) {
+	switch {
+	case x >= minIndexable:
+		s.pos.remove(s.index(x))
+	case x <= -minIndexable:
+		s.neg.remove(s.index(-x))
+	default:
+		s.zeros--
+	}
+}
+
+func (s *sketch) kth(k int) float64 {
+	// Most negative first: the largest magnitude buckets of neg.
+	for j := len(s.neg.index) - 1; j >= 0; j-- {
+		if k -= s.neg.count[j]; k <= 0 {
+			return -s.value(s.neg.index[j])
+		}
+	}
+	if k -= s.zeros; k <= 0 {
+		return 0
+	}
+	for j, i := range s.pos.index {
+		if k -= s.pos.count[j]; k <= 0 {
+			return s.value(i)
+		}
+	}
+	if n := len(s.pos.index); n > 0 {
+		return s.value(s.pos.index[n-1])
+	}
+	return 0
+}
+
+func (s *sketch) reset() {
+	s.pos.reset()
+	s.neg.reset()
+	s.zeros = 0
+}
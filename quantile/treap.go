@@ -0,0 +1,165 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package quantile
+
+// node is a distinct sample in the treap. Nodes are ordered by key as a
+// binary search tree and by prio as a max-heap, which keeps the tree
+// balanced in expectation.
+type node struct {
+	key         float64
+	count, size int // copies of key, and samples in the subtree
+	prio        uint32
+	left, right *node
+}
+
+func (t *node) total() int {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+func (t *node) update() {
+	t.size = t.count + t.left.total() + t.right.total()
+}
+
+// treap is the exact estimator.
+type treap struct {
+	root *node
+	seed uint32
+}
+
+func newTreap() *treap {
+	return &treap{seed: 2463534242}
+}
+
+// rand returns the next xorshift priority.
+func (tr *treap) rand() uint32 {
+	tr.seed ^= tr.seed << 13
+	tr.seed ^= tr.seed >> 17
+	tr.seed ^= tr.seed << 5
+	return tr.seed
+}
+
+func (tr *treap) insert(x float64) {
+	tr.root = tr.insertAt(tr.root, x)
+}
+
+func (tr *treap) insertAt(t *node, x float64) *node {
+	if t == nil {
+		return &node{key: x, count: 1, size: 1, prio: tr.rand()}
+	}
+	switch {
+	case x < t.key:
+		t.left = tr.insertAt(t.left, x)
+		if t.left.prio > t.prio {
+			t = rotateRight(t)
+		}
+	case x > t.key:
+		t.right = tr.insertAt(t.right, x)
+		if t.right.prio > t.prio {
+			t = rotateLeft(t)
+		}
+	default:
+		t.count++
+	}
+	t.update()
+	return t
+}
+
+func (tr *treap) remove(x float64) {
+	tr.root = removeAt(tr.root, x)
+}
+
+func removeAt(t *node, x float64) *node {
+	if t == nil {
+		return nil
+	}
+	switch {
+	case x < t.key:
+		t.left = removeAt(t.left, x)
+	case x > t.key:
+		t.right = removeAt(t.right, x)
+	case t.count > 1:
+		t.count--
+	default:
+		return merge(t.left, t.right)
+	}
+	t.update()
+	return t
+}
+
+func (tr *treap) kth(k int) float64 {
+	t := tr.root
+	for t != nil {
+		left := t.left.total()
+		switch {
+		case k <= left:
+			t = t.left
+		case k <= left+t.count:
+			return t.key
+		default:
+			k -= left + t.count
+			t = t.right
+		}
+	}
+	return 0
+}
+
+func (tr *treap) reset() {
+	tr.root = nil
+}
+
+// rotateRight lifts t.left above t.
+func rotateRight(t *node) *node {
+	l := t.left
+	t.left = l.right
+	t.update()
+	l.right = t
+	return l
+}
+
+// rotateLeft lifts t.right above t.
+func rotateLeft(t *node) *node {
+	r := t.right
+	t.right = r.left
+	t.update()
+	r.left = t
+	return r
+}
+
+// merge joins two treaps where every key of a is below every key of b.
+func merge(a, b *node) *node {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.prio > b.prio {
+		a.right = merge(a.right, b)
+		a.update()
+		return a
+	}
+	b.left = merge(a, b.left)
+	b.update()
+	return b
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package rolling keeps running statistics over the last N numeric samples
+// held in a circular buffer. Sum, mean and variance are updated on every
+// Push with Welford's method, removing the sample the buffer drops, and
+// minimum and maximum are kept in monotonic windows, so each Push costs
+// amortized O(1) whatever the size of the window.
+package rolling
+
+import (
+	"math"
+
+	"github.com/gus-maurizio/structures/circularbuffer"
+	"github.com/gus-maurizio/structures/monotonic"
+)
+
+// Number is the set of sample types a Window accepts.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Window holds the last Size() samples pushed and their statistics.
+type Window[T Number] struct {
+	buf  *circularbuffer.CircularBuffer[T]
+	n    int
+	sum  float64
+	mean float64
+	m2   float64
+	maxw *monotonic.Window[T]
+	minw *monotonic.Window[T]
+}
+
+// New returns an empty window of size samples. A size below 1 is taken
+// as 1.
+func New[T Number](size int) *Window[T] {
+	if size < 1 {
+		size = 1
+	}
+	return &Window[T]{
+		buf:  circularbuffer.New(size, T(0)),
+		maxw: monotonic.NewMax[T](size),
+		minw: monotonic.NewMin[T](size),
+	}
+}
+
+// Size returns the number of samples the window holds when full.
+func (w *Window[T]) Size() int {
+	return w.buf.Len
+}
+
+// Len returns the number of samples in the window.
+func (w *Window[T]) Len() int {
+	return w.n
+}
+
+// Push adds v to the window. Once the window is full it drops the oldest
+// sample, which is returned with true.
+func (w *Window[T]) Push(v T) (T, bool) {
+	old := w.buf.Push(v)
+	evicted := w.n == w.buf.Len
+	if evicted {
+		w.remove(float64(old))
+	}
+	w.add(float64(v))
+	w.maxw.Push(v)
+	w.minw.Push(v)
+
+	if !evicted {
+		return T(0), false
+	}
+	return old, true
+}
+
+// add accounts for a new sample x.
+func (w *Window[T]) add(x float64) {
+	w.n++
+	w.sum += x
+	d := x - w.mean
+	w.mean += d / float64(w.n)
+	w.m2 += d * (x - w.mean)
+}
+
+// remove accounts for dropping the sample x.
+func (w *Window[T]) remove(x float64) {
+	w.n--
+	if w.n == 0 {
+		w.sum, w.mean, w.m2 = 0, 0, 0
+		return
+	}
+	w.sum -= x
+	d := x - w.mean
+	w.mean -= d / float64(w.n)
+	w.m2 -= d * (x - w.mean)
+	// Rounding can leave a tiny negative sum of squares.
+	if w.m2 < 0 {
+		w.m2 = 0
+	}
+}
+
+// Sum returns the sum of the samples in the window.
+func (w *Window[T]) Sum() float64 {
+	return w.sum
+}
+
+// Mean returns the mean of the samples in the window, 0 if it is empty.
+func (w *Window[T]) Mean() float64 {
+	return w.mean
+}
+
+// Variance returns the population variance of the samples in the window.
+func (w *Window[T]) Variance() float64 {
+	if w.n == 0 {
+		return 0
+	}
+	return w.m2 / float64(w.n)
+}
+
+// SampleVariance returns the sample (n-1) variance of the samples in the
+// window, 0 if there are fewer than two.
+func (w *Window[T]) SampleVariance() float64 {
+	if w.n < 2 {
+		return 0
+	}
+	return w.m2 / float64(w.n-1)
+}
+
+// StdDev returns the population standard deviation of the samples.
+func (w *Window[T]) StdDev() float64 {
+	return math.Sqrt(w.Variance())
+}
+
+// Min returns the smallest sample in the window, false if it is empty.
+func (w *Window[T]) Min() (T, bool) {
+	return w.minw.Value()
+}
+
+// Max returns the largest sample in the window, false if it is empty.
+func (w *Window[T]) Max() (T, bool) {
+	return w.maxw.Value()
+}
+
+// Values returns the samples in the window, oldest first, in a new slice.
+func (w *Window[T]) Values() []T {
+	values := w.buf.GetValues()
+	return values[len(values)-w.n:]
+}
+
+// Reset empties the window.
+func (w *Window[T]) Reset() {
+	w.buf.Init(T(0))
+	w.n = 0
+	w.sum, w.mean, w.m2 = 0, 0, 0
+	w.maxw.Reset()
+	w.minw.Reset()
+}
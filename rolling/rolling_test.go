@@ -0,0 +1,117 @@
+package rolling
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// naive computes the statistics of values the slow way.
+func naive(values []float64) (sum, mean, variance, lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		sum += v
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	mean = sum / float64(len(values))
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values))
+	return
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(b))
+}
+
+func TestEmpty(t *testing.T) {
+	w := New[int](4)
+	if w.Len() != 0 || w.Mean() != 0 || w.Variance() != 0 {
+		t.Error("empty window has statistics")
+	}
+	if _, ok := w.Min(); ok {
+		t.Error("Min() on empty window returned ok")
+	}
+	if _, ok := w.Max(); ok {
+		t.Error("Max() on empty window returned ok")
+	}
+}
+
+func TestPushEvicts(t *testing.T) {
+	w := New[int](3)
+	for i := 1; i <= 3; i++ {
+		if _, evicted := w.Push(i); evicted {
+			t.Error("Push", i, "evicted before window was full")
+		}
+	}
+	if old, evicted := w.Push(4); !evicted || old != 1 {
+		t.Error("Push(4) =", old, evicted, "expected 1 true")
+	}
+	if !slices.Equal(w.Values(), []int{2, 3, 4}) {
+		t.Error("Values() =", w.Values())
+	}
+	if w.Sum() != 9 || w.Mean() != 3 {
+		t.Error("Sum() =", w.Sum(), "Mean() =", w.Mean())
+	}
+	if v := w.SampleVariance(); v != 1 {
+		t.Error("SampleVariance() =", v, "expected 1")
+	}
+}
+
+func TestAgainstNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{1, 2, 7, 64} {
+		w := New[float64](size)
+		var all []float64
+		for i := 0; i < 2000; i++ {
+			// A slow trend plus noise, with a few spikes.
+			v := float64(i%300) + r.NormFloat64()*10
+			if r.Intn(50) == 0 {
+				v *= 10
+			}
+			w.Push(v)
+			all = append(all, v)
+
+			last := all[max(0, len(all)-size):]
+			sum, mean, variance, lo, hi := naive(last)
+			if w.Len() != len(last) {
+				t.Fatalf("size %d step %d: Len() = %d", size, i, w.Len())
+			}
+			if !near(w.Sum(), sum) || !near(w.Mean(), mean) || !near(w.Variance(), variance) {
+				t.Fatalf("size %d step %d: sum %v mean %v variance %v, expected %v %v %v",
+					size, i, w.Sum(), w.Mean(), w.Variance(), sum, mean, variance)
+			}
+			if m, _ := w.Min(); m != lo {
+				t.Fatalf("size %d step %d: Min() = %v, expected %v", size, i, m, lo)
+			}
+			if m, _ := w.Max(); m != hi {
+				t.Fatalf("size %d step %d: Max() = %v, expected %v", size, i, m, hi)
+			}
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	w := New[int32](4)
+	for i := int32(0); i < 10; i++ {
+		w.Push(i)
+	}
+	w.Reset()
+	if w.Len() != 0 || w.Sum() != 0 {
+		t.Error("Reset() left samples")
+	}
+	w.Push(-5)
+	if m, _ := w.Max(); m != -5 || w.Mean() != -5 {
+		t.Error("after Reset() Max() =", m, "Mean() =", w.Mean())
+	}
+}
+
+func BenchmarkPush(b *testing.B) {
+	w := New[float64](1024)
+	for i := 0; i < b.N; i++ {
+		w.Push(float64(i % 4096))
+	}
+}
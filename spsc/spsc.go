@@ -0,0 +1,106 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package spsc provides a bounded lock-free ring buffer for exactly one
+// producer goroutine and one consumer goroutine. Like deque it keeps a
+// power of 2 buffer and wraps its indexes with a bitwise modulus; the head
+// and tail indexes are atomics kept on separate cache lines so the two
+// goroutines do not invalidate each other's line on every operation.
+package spsc
+
+import "sync/atomic"
+
+// cacheLine is the padding that keeps the producer's and the consumer's
+// fields of a Ring on different cache lines.
+const cacheLine = 64
+
+// Ring is a bounded single-producer/single-consumer queue. TryPush may
+// only be called from one goroutine at a time, and TryPop from one
+// (possibly other) goroutine at a time.
+type Ring[T any] struct {
+	_ [cacheLine]byte
+	// head is the next position to read, written only by the consumer.
+	head atomic.Uint64
+	// tailCache is the consumer's last view of tail.
+	tailCache uint64
+	_         [cacheLine - 16]byte
+	// tail is the next position to write, written only by the producer.
+	tail atomic.Uint64
+	// headCache is the producer's last view of head.
+	headCache uint64
+	_         [cacheLine - 16]byte
+	mask      uint64
+	buffer    []T
+}
+
+// New returns an empty ring holding at least capacity elements. The
+// capacity is rounded up to a power of 2.
+func New[T any](capacity int) *Ring[T] {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	return &Ring[T]{mask: uint64(size - 1), buffer: make([]T, size)}
+}
+
+// Cap returns the number of elements the ring holds when full.
+func (r *Ring[T]) Cap() int {
+	return len(r.buffer)
+}
+
+// Len returns the number of elements in the ring. With the producer and
+// consumer running it is only a snapshot.
+func (r *Ring[T]) Len() int {
+	return int(r.tail.Load() - r.head.Load())
+}
+
+// TryPush appends v to the ring. It returns false, without waiting, if
+// the ring is full. Only the producer goroutine may call it.
+func (r *Ring[T]) TryPush(v T) bool {
+	t := r.tail.Load()
+	if t-r.headCache == uint64(len(r.buffer)) {
+		r.headCache = r.head.Load()
+		if t-r.headCache == uint64(len(r.buffer)) {
+			return false
+		}
+	}
+	r.buffer[t&r.mask] = v // bitwise modulus
+	r.tail.Store(t + 1)
+	return true
+}
+
+// TryPop removes and returns the oldest element of the ring. It returns
+// false, without waiting, if the ring is empty. Only the consumer
+// goroutine may call it.
+func (r *Ring[T]) TryPop() (T, bool) {
+	var zero T
+	h := r.head.Load()
+	if h == r.tailCache {
+		r.tailCache = r.tail.Load()
+		if h == r.tailCache {
+			return zero, false
+		}
+	}
+	i := h & r.mask // bitwise modulus
+	v := r.buffer[i]
+	r.buffer[i] = zero
+	r.head.Store(h + 1)
+	return v, true
+}
@@ -0,0 +1,115 @@
+package spsc
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/gus-maurizio/structures/circularbuffer"
+)
+
+func TestCapacity(t *testing.T) {
+	for capacity, want := range map[int]int{0: 1, 1: 1, 3: 4, 64: 64, 100: 128} {
+		if got := New[int](capacity).Cap(); got != want {
+			t.Error("New", capacity, "has Cap", got, "expected", want)
+		}
+	}
+}
+
+func TestPadding(t *testing.T) {
+	var r Ring[int]
+	head := unsafe.Offsetof(r.head)
+	tail := unsafe.Offsetof(r.tail)
+	if tail-head < cacheLine {
+		t.Error("head and tail share a cache line")
+	}
+}
+
+func TestFullEmpty(t *testing.T) {
+	r := New[int](4)
+	if _, ok := r.TryPop(); ok {
+		t.Error("TryPop succeeded on empty ring")
+	}
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 4; i++ {
+			if !r.TryPush(i) {
+				t.Fatal("TryPush", i, "failed")
+			}
+		}
+		if r.TryPush(4) {
+			t.Error("TryPush succeeded on full ring")
+		}
+		if r.Len() != 4 {
+			t.Error("r.Len() =", r.Len(), "expected 4")
+		}
+		for i := 0; i < 4; i++ {
+			if v, ok := r.TryPop(); !ok || v != i {
+				t.Error("TryPop() =", v, ok, "expected", i)
+			}
+		}
+	}
+}
+
+func TestProducerConsumer(t *testing.T) {
+	r := New[int](16)
+	const items = 100000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < items; i++ {
+			for !r.TryPush(i) {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for want := 0; want < items; {
+		v, ok := r.TryPop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		if v != want {
+			t.Fatal("TryPop() =", v, "expected", want)
+		}
+		want++
+	}
+	wg.Wait()
+}
+
+func BenchmarkRing(b *testing.B) {
+	r := New[int](1024)
+	done := make(chan struct{})
+	go func() {
+		for n := 0; n < b.N; {
+			if _, ok := r.TryPop(); ok {
+				n++
+			} else {
+				runtime.Gosched()
+			}
+		}
+		close(done)
+	}()
+	for i := 0; i < b.N; i++ {
+		for !r.TryPush(i) {
+			runtime.Gosched()
+		}
+	}
+	<-done
+}
+
+func BenchmarkSafeCircularBuffer(b *testing.B) {
+	c := circularbuffer.NewSafe(1024, 0)
+	done := make(chan struct{})
+	go func() {
+		for n := 0; n < b.N; n++ {
+			c.Get(-1)
+		}
+		close(done)
+	}()
+	for i := 0; i < b.N; i++ {
+		c.Push(i)
+	}
+	<-done
+}
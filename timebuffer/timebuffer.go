@@ -0,0 +1,194 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package timebuffer provides a rolling buffer that evicts by age instead
+// of by count: every value is stored with a timestamp and is dropped once
+// it is older than the span of the buffer. Time is read from a clock
+// function that tests can replace to run deterministically.
+package timebuffer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gus-maurizio/structures/deque/v2"
+)
+
+// Entry is a value and the time it was pushed.
+type Entry[T any] struct {
+	Time  time.Time
+	Value T
+}
+
+// Buffer holds the values pushed during the last Span() of time, oldest
+// first.
+type Buffer[T any] struct {
+	q      deque.Deque[Entry[T]]
+	span   time.Duration
+	now    func() time.Time
+	maxLen int
+	evict  func(Entry[T])
+}
+
+// Option configures a Buffer when passed to New.
+type Option[T any] func(*Buffer[T])
+
+// WithClock makes the buffer read the current time from now instead of
+// time.Now.
+func WithClock[T any](now func() time.Time) Option[T] {
+	return func(b *Buffer[T]) { b.now = now }
+}
+
+// WithMaxLen bounds the buffer to n entries; a Push on a full buffer
+// evicts the oldest entry whatever its age.
+func WithMaxLen[T any](n int) Option[T] {
+	return func(b *Buffer[T]) { b.maxLen = n }
+}
+
+// WithEvict registers f to be called with every entry the buffer drops.
+func WithEvict[T any](f func(Entry[T])) Option[T] {
+	return func(b *Buffer[T]) { b.evict = f }
+}
+
+// New returns an empty buffer keeping values for span.
+func New[T any](span time.Duration, opts ...Option[T]) *Buffer[T] {
+	b := &Buffer[T]{span: span, now: time.Now}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Span returns how long the buffer keeps values.
+func (b *Buffer[T]) Span() time.Duration {
+	return b.span
+}
+
+// Push stores v with the current time and drops the entries that aged
+// out.
+func (b *Buffer[T]) Push(v T) {
+	b.PushAt(b.now(), v)
+}
+
+// PushAt stores v with time t. Times earlier than the newest entry are
+// moved up to it, so entries stay in time order.
+func (b *Buffer[T]) PushAt(t time.Time, v T) {
+	if last, ok := b.q.Last(); ok && t.Before(last.Time) {
+		t = last.Time
+	}
+	if b.maxLen > 0 && b.q.Len() >= b.maxLen {
+		b.drop()
+	}
+	b.q.PushLast(Entry[T]{t, v})
+	b.Expire()
+}
+
+// Expire drops the entries older than Span() at the current time.
+func (b *Buffer[T]) Expire() {
+	cutoff := b.now().Add(-b.span)
+	for e, ok := b.q.First(); ok && e.Time.Before(cutoff); e, ok = b.q.First() {
+		b.drop()
+	}
+}
+
+// drop removes the oldest entry.
+func (b *Buffer[T]) drop() {
+	e, _ := b.q.PopFirst()
+	if b.evict != nil {
+		b.evict(e)
+	}
+}
+
+// Len returns the number of entries held, including those that aged out
+// since the last Push or Expire.
+func (b *Buffer[T]) Len() int {
+	return b.q.Len()
+}
+
+// CountInWindow expires old entries and returns how many are left.
+func (b *Buffer[T]) CountInWindow() int {
+	b.Expire()
+	return b.q.Len()
+}
+
+// Oldest returns the oldest entry in the window, false if it is empty.
+func (b *Buffer[T]) Oldest() (Entry[T], bool) {
+	b.Expire()
+	return b.q.First()
+}
+
+// Newest returns the newest entry in the window, false if it is empty.
+func (b *Buffer[T]) Newest() (Entry[T], bool) {
+	b.Expire()
+	return b.q.Last()
+}
+
+// since returns the index of the first entry at or after t.
+func (b *Buffer[T]) since(t time.Time) int {
+	return sort.Search(b.q.Len(), func(i int) bool {
+		e, _ := b.q.At(i)
+		return !e.Time.Before(t)
+	})
+}
+
+// ValuesSince expires old entries and returns, oldest first, the values
+// pushed at or after t.
+func (b *Buffer[T]) ValuesSince(t time.Time) []T {
+	b.Expire()
+	i := b.since(t)
+	values := make([]T, 0, b.q.Len()-i)
+	for ; i < b.q.Len(); i++ {
+		e, _ := b.q.At(i)
+		values = append(values, e.Value)
+	}
+	return values
+}
+
+// CountSince expires old entries and returns how many were pushed at or
+// after t.
+func (b *Buffer[T]) CountSince(t time.Time) int {
+	b.Expire()
+	return b.q.Len() - b.since(t)
+}
+
+// Values expires old entries and returns the values left, oldest first.
+func (b *Buffer[T]) Values() []T {
+	b.Expire()
+	values := make([]T, 0, b.q.Len())
+	for e := range b.q.Values() {
+		values = append(values, e.Value)
+	}
+	return values
+}
+
+// Entries expires old entries and returns those left, oldest first.
+func (b *Buffer[T]) Entries() []Entry[T] {
+	b.Expire()
+	entries := make([]Entry[T], 0, b.q.Len())
+	for e := range b.q.Values() {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Clear drops every entry without calling the evict function.
+func (b *Buffer[T]) Clear() {
+	b.q.Clear()
+}
@@ -0,0 +1,90 @@
+package timebuffer
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock the tests move by hand.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time      { return c.t }
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+
+func newClock() *fakeClock { return &fakeClock{time.Unix(1_500_000_000, 0)} }
+
+func TestEvictByAge(t *testing.T) {
+	clock := newClock()
+	var evicted []int
+	b := New(5*time.Minute,
+		WithClock[int](clock.now),
+		WithEvict(func(e Entry[int]) { evicted = append(evicted, e.Value) }))
+
+	for i := 0; i < 10; i++ {
+		b.Push(i)
+		clock.add(time.Minute)
+	}
+	// Now is 10m after the first push; the window is [5m, 10m].
+	if n := b.CountInWindow(); n != 5 {
+		t.Error("CountInWindow() =", n, "expected 5")
+	}
+	if got := b.Values(); !slices.Equal(got, []int{5, 6, 7, 8, 9}) {
+		t.Error("Values() =", got)
+	}
+	if !slices.Equal(evicted, []int{0, 1, 2, 3, 4}) {
+		t.Error("evicted", evicted)
+	}
+
+	clock.add(time.Hour)
+	if n := b.CountInWindow(); n != 0 {
+		t.Error("CountInWindow() after an hour =", n)
+	}
+	if _, ok := b.Newest(); ok {
+		t.Error("Newest() on aged out buffer returned ok")
+	}
+}
+
+func TestValuesSince(t *testing.T) {
+	clock := newClock()
+	start := clock.now()
+	b := New[string](time.Hour, WithClock[string](clock.now))
+	for _, s := range []string{"a", "b", "c", "d"} {
+		b.Push(s)
+		clock.add(10 * time.Second)
+	}
+	if got := b.ValuesSince(start.Add(15 * time.Second)); !slices.Equal(got, []string{"c", "d"}) {
+		t.Error("ValuesSince(15s) =", got)
+	}
+	if got := b.ValuesSince(start.Add(10 * time.Second)); !slices.Equal(got, []string{"b", "c", "d"}) {
+		t.Error("ValuesSince(10s) =", got)
+	}
+	if n := b.CountSince(start.Add(time.Minute)); n != 0 {
+		t.Error("CountSince(1m) =", n)
+	}
+	if e, _ := b.Oldest(); e.Value != "a" || !e.Time.Equal(start) {
+		t.Error("Oldest() =", e)
+	}
+}
+
+func TestPushAtOrder(t *testing.T) {
+	clock := newClock()
+	b := New[int](time.Minute, WithClock[int](clock.now))
+	b.PushAt(clock.now(), 1)
+	b.PushAt(clock.now().Add(-30*time.Second), 2)
+	entries := b.Entries()
+	if len(entries) != 2 || !entries[1].Time.Equal(entries[0].Time) {
+		t.Error("out of order PushAt stored", entries)
+	}
+}
+
+func TestMaxLen(t *testing.T) {
+	clock := newClock()
+	b := New[int](time.Hour, WithClock[int](clock.now), WithMaxLen[int](3))
+	for i := 0; i < 5; i++ {
+		b.Push(i)
+	}
+	if got := b.Values(); !slices.Equal(got, []int{2, 3, 4}) {
+		t.Error("Values() =", got)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package timingwheel schedules large numbers of timers cheaply with a
+// hierarchical hashed timing wheel. Each wheel is a ring of slots, each
+// slot a Deque of timers, with a cursor that advances one slot per tick as
+// circularbuffer's head does. A timer due within one turn of the first
+// wheel goes straight into it; later timers go into coarser wheels whose
+// slots span a whole turn of the wheel below, and cascade down as their
+// time approaches. Scheduling, cancelling and firing a timer take
+// constant time.
+package timingwheel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gus-maurizio/structures/deque/v2"
+)
+
+const (
+	defaultSlots  = 64
+	defaultLevels = 4
+)
+
+// Timer states.
+const (
+	pending = iota
+	fired
+	cancelled
+)
+
+// Timer is a function scheduled to run on a Wheel.
+type Timer struct {
+	w        *Wheel
+	deadline uint64
+	f        func()
+	state    int
+}
+
+// Deadline returns the tick at which the timer fires.
+func (t *Timer) Deadline() uint64 { return t.deadline }
+
+// Stop cancels the timer, as Wheel.Cancel does.
+func (t *Timer) Stop() bool { return t.w.Cancel(t) }
+
+// Wheel is a hierarchical timing wheel safe for use by several
+// goroutines. Timer functions run one at a time, on the goroutine that
+// advances the wheel, and should be quick; they may schedule and cancel
+// timers.
+type Wheel struct {
+	tick   time.Duration
+	bits   uint
+	mask   uint64
+	levels int
+	manual bool
+
+	mu     sync.Mutex
+	now    uint64
+	wheels [][]deque.Deque[*Timer]
+	count  int
+
+	advancing sync.Mutex // serializes Advance
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// Option configures a Wheel when passed to New.
+type Option func(*Wheel)
+
+// WithSlots sets the number of slots of each wheel, 64 by default. It is
+// rounded up to a power of 2, and is at least 2.
+func WithSlots(n int) Option {
+	return func(w *Wheel) {
+		w.bits = 1
+		for 1<<w.bits < n {
+			w.bits++
+		}
+	}
+}
+
+// WithLevels sets the number of wheels, 4 by default. Timers due after
+// the last wheel's span wait in its furthest slot and are placed again
+// each time it comes round. A level below 1 is taken as 1.
+func WithLevels(n int) Option {
+	return func(w *Wheel) { w.levels = max(n, 1) }
+}
+
+// WithManual makes the wheel advance only when Advance is called, for
+// deterministic tests and for callers that drive time themselves.
+func WithManual() Option {
+	return func(w *Wheel) { w.manual = true }
+}
+
+// New returns a wheel advancing one slot every tick. Unless WithManual is
+// given it starts a goroutine that advances it in real time until Stop.
+func New(tick time.Duration, opts ...Option) *Wheel {
+	w := &Wheel{tick: tick, levels: defaultLevels}
+	WithSlots(defaultSlots)(w)
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.mask = 1<<w.bits - 1
+	w.wheels = make([][]deque.Deque[*Timer], w.levels)
+	for i := range w.wheels {
+		w.wheels[i] = make([]deque.Deque[*Timer], 1<<w.bits)
+	}
+	if !w.manual {
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		go w.run(w.stop)
+	}
+	return w
+}
+
+// Tick returns the duration of one tick.
+func (w *Wheel) Tick() time.Duration { return w.tick }
+
+// Now returns the number of ticks the wheel has advanced.
+func (w *Wheel) Now() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.now
+}
+
+// Len returns the number of pending timers.
+func (w *Wheel) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Schedule runs f once delay has passed, rounded up to whole ticks and at
+// least one tick from now.
+func (w *Wheel) Schedule(delay time.Duration, f func()) *Timer {
+	ticks := uint64(1)
+	if delay > w.tick {
+		ticks = uint64((delay + w.tick - 1) / w.tick)
+	}
+	return w.ScheduleTicks(ticks, f)
+}
+
+// ScheduleTicks runs f once the wheel has advanced ticks more times. A
+// ticks of 0 is taken as 1.
+func (w *Wheel) ScheduleTicks(ticks uint64, f func()) *Timer {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t := &Timer{w: w, deadline: w.now + max(ticks, 1), f: f}
+	w.place(t)
+	w.count++
+	return t
+}
+
+// Cancel stops t from firing and reports whether it was pending. The
+// timer stays in its slot until the wheel reaches it, but is skipped.
+func (w *Wheel) Cancel(t *Timer) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if t.state != pending {
+		return false
+	}
+	t.state = cancelled
+	w.count--
+	return true
+}
+
+// Advance moves the wheel on by ticks, running the timers that fall due
+// after each tick, and returns the number of timers run.
+func (w *Wheel) Advance(ticks int) int {
+	w.advancing.Lock()
+	defer w.advancing.Unlock()
+	n := 0
+	var due []*Timer
+	for i := 0; i < ticks; i++ {
+		w.mu.Lock()
+		due = w.step(due[:0])
+		w.mu.Unlock()
+		for _, t := range due {
+			t.f()
+		}
+		n += len(due)
+	}
+	return n
+}
+
+// Stop stops the goroutine advancing the wheel in real time. Pending
+// timers do not fire unless Advance is called. Stop has no effect on a
+// manual wheel or a stopped one.
+func (w *Wheel) Stop() {
+	w.mu.Lock()
+	stop := w.stop
+	w.stop = nil
+	w.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-w.done
+	}
+}
+
+// run advances the wheel by the ticks elapsed since it started, catching
+// up on ticks missed while timer functions ran.
+func (w *Wheel) run(stop <-chan struct{}) {
+	defer close(w.done)
+	start := time.Now()
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			elapsed := uint64(now.Sub(start) / w.tick)
+			if n := w.Now(); elapsed > n {
+				w.Advance(int(elapsed - n))
+			}
+		}
+	}
+}
+
+// step advances the wheel one tick, cascading coarser wheels whose turn
+// has come, and appends the timers now due to due.
+func (w *Wheel) step(due []*Timer) []*Timer {
+	w.now++
+	// Cascade from the coarsest wheel down, so that timers move through
+	// each wheel whose slot comes round on this tick.
+	for l := w.levels - 1; l > 0; l-- {
+		if w.now&(1<<(w.bits*uint(l))-1) != 0 {
+			continue
+		}
+		slot := &w.wheels[l][(w.now>>(w.bits*uint(l)))&w.mask]
+		for n := slot.Len(); n > 0; n-- {
+			t, _ := slot.PopFirst()
+			if t.state == pending {
+				w.place(t)
+			}
+		}
+	}
+	slot := &w.wheels[0][w.now&w.mask]
+	for n := slot.Len(); n > 0; n-- {
+		t, _ := slot.PopFirst()
+		if t.state != pending {
+			continue
+		}
+		if t.deadline > w.now {
+			// Parked beyond the last wheel's span; place it again.
+			w.place(t)
+		} else {
+			t.state = fired
+			w.count--
+			due = append(due, t)
+		}
+	}
+	return due
+}
+
+// place puts t in the slot of the finest wheel that reaches its deadline.
+func (w *Wheel) place(t *Timer) {
+	at := t.deadline
+	span := uint64(1) << (w.bits * uint(w.levels))
+	if w.bits*uint(w.levels) >= 64 {
+		span = 0
+	}
+	if span != 0 && at-w.now >= span {
+		// Beyond the last wheel: wait in its furthest slot.
+		at = w.now + span - 1
+	}
+	l := 0
+	for l < w.levels-1 && at-w.now >= 1<<(w.bits*uint(l+1)) {
+		l++
+	}
+	w.wheels[l][(at>>(w.bits*uint(l)))&w.mask].PushLast(t)
+}
@@ -0,0 +1,124 @@
+package timingwheel
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFireOrder(t *testing.T) {
+	w := New(time.Millisecond, WithManual())
+	var fired []int
+	for _, d := range []uint64{3, 1, 2, 1} {
+		w.ScheduleTicks(d, func() { fired = append(fired, int(d)) })
+	}
+	if w.Len() != 4 {
+		t.Fatal("Len() =", w.Len())
+	}
+	if n := w.Advance(1); n != 2 || !slices.Equal(fired, []int{1, 1}) {
+		t.Fatal("first tick ran", n, fired)
+	}
+	if n := w.Advance(5); n != 2 || !slices.Equal(fired, []int{1, 1, 2, 3}) {
+		t.Fatal("next ticks ran", n, fired)
+	}
+	if w.Now() != 6 || w.Len() != 0 {
+		t.Error("Now, Len =", w.Now(), w.Len())
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	w := New(10*time.Millisecond, WithManual())
+	cases := map[time.Duration]uint64{0: 1, time.Millisecond: 1, 10 * time.Millisecond: 1, 11 * time.Millisecond: 2, time.Second: 100}
+	for delay, want := range cases {
+		if got := w.Schedule(delay, func() {}).Deadline(); got != want {
+			t.Errorf("Schedule(%v) deadline %d, expected %d", delay, got, want)
+		}
+	}
+}
+
+// TestCascade checks that timers spread over every wheel, and beyond the
+// last one, each fire exactly on their deadline.
+func TestCascade(t *testing.T) {
+	r := rand.New(rand.NewPCG(5, 6))
+	deadlines := []uint64{1, 3, 4, 5, 10, 15, 16, 17, 63, 64, 65, 200, 1000}
+	for i := 0; i < 300; i++ {
+		deadlines = append(deadlines, 1+r.Uint64N(500))
+	}
+	for _, levels := range []int{1, 2, 3} {
+		w := New(time.Millisecond, WithManual(), WithSlots(4), WithLevels(levels))
+		count, late := 0, 0
+		for _, d := range deadlines {
+			w.ScheduleTicks(d, func() {
+				count++
+				if w.Now() != d {
+					late++
+				}
+			})
+		}
+		w.Advance(1000)
+		if count != len(deadlines) || late != 0 {
+			t.Errorf("%d levels: fired %d of %d timers, %d at the wrong tick", levels, count, len(deadlines), late)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	w := New(time.Millisecond, WithManual())
+	ran := 0
+	a := w.ScheduleTicks(5, func() { ran++ })
+	b := w.ScheduleTicks(500, func() { ran++ })
+	if !a.Stop() || a.Stop() || !w.Cancel(b) || w.Len() != 0 {
+		t.Fatal("Cancel misreported")
+	}
+	c := w.ScheduleTicks(1, func() { ran++ })
+	w.Advance(600)
+	if ran != 1 || c.Stop() {
+		t.Error("ran", ran, "timers")
+	}
+}
+
+func TestRescheduleFromCallback(t *testing.T) {
+	w := New(time.Millisecond, WithManual())
+	var ticks []uint64
+	var again func()
+	again = func() {
+		ticks = append(ticks, w.Now())
+		if len(ticks) < 4 {
+			w.ScheduleTicks(2, again)
+		}
+	}
+	w.ScheduleTicks(1, again)
+	w.Advance(10)
+	if !slices.Equal(ticks, []uint64{1, 3, 5, 7}) {
+		t.Error("callback ran at", ticks)
+	}
+}
+
+func TestRealTime(t *testing.T) {
+	w := New(time.Millisecond)
+	defer w.Stop()
+	done := make(chan struct{})
+	w.Schedule(5*time.Millisecond, func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timer did not fire")
+	}
+	w.Stop()
+	now := w.Now()
+	time.Sleep(5 * time.Millisecond)
+	if w.Now() != now {
+		t.Error("wheel advanced after Stop")
+	}
+}
+
+func BenchmarkScheduleCancel(b *testing.B) {
+	w := New(time.Millisecond, WithManual())
+	for i := 0; i < b.N; i++ {
+		w.ScheduleTicks(uint64(i%100000), func() {}).Stop()
+		if i%64 == 0 {
+			w.Advance(1)
+		}
+	}
+}
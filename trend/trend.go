@@ -0,0 +1,230 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package trend computes smoothed values, rates and derivatives from the
+// samples pushed into a circular buffer. Each calculator hands out a
+// circularbuffer.Option that hooks it into the buffer's Push, so its
+// result always matches the samples the buffer holds.
+//
+//	load := trend.NewEWMASamples(60)
+//	buf := circularbuffer.New(300, 0.0, load.Option())
+//	buf.Push(0.7)
+//	fmt.Println(load.Value())
+package trend
+
+import (
+	"math"
+	"time"
+
+	"github.com/gus-maurizio/structures/circularbuffer"
+)
+
+// Sample is a value measured at a point in time.
+type Sample struct {
+	At    time.Time
+	Value float64
+}
+
+// EWMA is an exponentially weighted moving average. Each update moves the
+// average a fraction alpha of the way towards the new value; the first
+// update sets it.
+type EWMA struct {
+	alpha    float64
+	halfLife time.Duration
+	value    float64
+	last     time.Time
+	started  bool
+}
+
+// NewEWMA returns an average with smoothing factor alpha, 0 < alpha <= 1.
+func NewEWMA(alpha float64) *EWMA {
+	return &EWMA{alpha: alpha}
+}
+
+// NewEWMASamples returns an average in which a value weighs half as much
+// after halfLife more updates.
+func NewEWMASamples(halfLife int) *EWMA {
+	return NewEWMA(1 - math.Exp2(-1/float64(max(halfLife, 1))))
+}
+
+// NewEWMATime returns an average for irregular samples, in which a value
+// weighs half as much after halfLife of time. It is meant to be updated
+// with UpdateAt; Update takes the value as measured at the time of the
+// call.
+func NewEWMATime(halfLife time.Duration) *EWMA {
+	return &EWMA{halfLife: halfLife}
+}
+
+// Update adds x to the average.
+func (e *EWMA) Update(x float64) {
+	if e.halfLife > 0 {
+		e.UpdateAt(time.Now(), x)
+		return
+	}
+	e.add(x, e.alpha)
+}
+
+// UpdateAt adds x measured at t to the average. For an average made with
+// NewEWMATime the weight of the previous average decays with the time
+// since the last update, and a sample older than the last one has no
+// weight; otherwise t is ignored.
+func (e *EWMA) UpdateAt(t time.Time, x float64) {
+	if e.halfLife <= 0 {
+		e.add(x, e.alpha)
+		return
+	}
+	alpha := 1.0
+	if e.started {
+		dt := max(t.Sub(e.last), 0)
+		alpha = 1 - math.Exp2(-float64(dt)/float64(e.halfLife))
+	}
+	if t.After(e.last) {
+		e.last = t
+	}
+	e.add(x, alpha)
+}
+
+// add moves the average a fraction alpha of the way towards x.
+func (e *EWMA) add(x, alpha float64) {
+	if !e.started {
+		e.value, e.started = x, true
+		return
+	}
+	e.value += alpha * (x - e.value)
+}
+
+// Value returns the average, 0 before the first update.
+func (e *EWMA) Value() float64 {
+	return e.value
+}
+
+// Reset forgets every update.
+func (e *EWMA) Reset() {
+	e.value, e.started, e.last = 0, false, time.Time{}
+}
+
+// Option hooks the average into the Push of a buffer of values. An
+// average made with NewEWMATime takes each value as measured when it is
+// pushed; use SampleOption to give it the time of the samples instead.
+func (e *EWMA) Option() circularbuffer.Option[float64] {
+	return circularbuffer.WithPush(func(value, _ float64) { e.Update(value) })
+}
+
+// SampleOption hooks the average into the Push of a buffer of samples.
+func (e *EWMA) SampleOption() circularbuffer.Option[Sample] {
+	return circularbuffer.WithPush(func(s, _ Sample) { e.UpdateAt(s.At, s.Value) })
+}
+
+// Rate measures how often events happen from the timestamps of the
+// last events, held in a circular buffer.
+type Rate struct {
+	buf *circularbuffer.CircularBuffer[time.Time]
+	n   int
+}
+
+// NewRate returns a rate to hook into a buffer with Option.
+func NewRate() *Rate {
+	return &Rate{}
+}
+
+// Option hooks the rate into the Push of a buffer of event timestamps.
+// Timestamps must be pushed in order. Init and Set on the buffer are not
+// seen by the rate.
+func (r *Rate) Option() circularbuffer.Option[time.Time] {
+	return func(c *circularbuffer.CircularBuffer[time.Time]) {
+		r.buf = c
+		circularbuffer.WithPush(func(_, _ time.Time) {
+			if r.n < c.Len {
+				r.n++
+			}
+		})(c)
+	}
+}
+
+// Count returns the number of events in the buffer.
+func (r *Rate) Count() int {
+	return r.n
+}
+
+// PerSecond returns the events per second between the oldest and newest
+// event in the buffer, 0 with fewer than two events.
+func (r *Rate) PerSecond() float64 {
+	if r.n < 2 {
+		return 0
+	}
+	span := r.buf.Get(-1).Sub(r.buf.Get(-r.n))
+	if span <= 0 {
+		return math.Inf(1)
+	}
+	return float64(r.n-1) / span.Seconds()
+}
+
+// Derivative measures how fast the value of the samples held in a
+// circular buffer changes, per second.
+type Derivative struct {
+	buf *circularbuffer.CircularBuffer[Sample]
+	n   int
+}
+
+// NewDerivative returns a derivative to hook into a buffer with Option.
+func NewDerivative() *Derivative {
+	return &Derivative{}
+}
+
+// Option hooks the derivative into the Push of a buffer of samples.
+// Samples must be pushed in time order. Init and Set on the buffer are
+// not seen by the derivative.
+func (d *Derivative) Option() circularbuffer.Option[Sample] {
+	return func(c *circularbuffer.CircularBuffer[Sample]) {
+		d.buf = c
+		circularbuffer.WithPush(func(_, _ Sample) {
+			if d.n < c.Len {
+				d.n++
+			}
+		})(c)
+	}
+}
+
+// Last returns the change per second between the two newest samples, and
+// false if there are fewer than two or they have the same time.
+func (d *Derivative) Last() (float64, bool) {
+	if d.n < 2 {
+		return 0, false
+	}
+	return slope(d.buf.Get(-2), d.buf.Get(-1))
+}
+
+// Average returns the change per second between the oldest and newest
+// samples in the buffer, and false if there are fewer than two or they
+// have the same time.
+func (d *Derivative) Average() (float64, bool) {
+	if d.n < 2 {
+		return 0, false
+	}
+	return slope(d.buf.Get(-d.n), d.buf.Get(-1))
+}
+
+func slope(a, b Sample) (float64, bool) {
+	dt := b.At.Sub(a.At).Seconds()
+	if dt <= 0 {
+		return 0, false
+	}
+	return (b.Value - a.Value) / dt, true
+}
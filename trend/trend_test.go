@@ -0,0 +1,135 @@
+package trend
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gus-maurizio/structures/circularbuffer"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestEWMA(t *testing.T) {
+	e := NewEWMA(0.5)
+	buf := circularbuffer.New(4, 0.0, e.Option())
+	if e.Value() != 0 {
+		t.Error("Value() before update =", e.Value())
+	}
+	for _, x := range []float64{8, 4, 4, 0} {
+		buf.Push(x)
+	}
+	// 8, then 6, 5, 2.5.
+	if !near(e.Value(), 2.5) {
+		t.Error("Value() =", e.Value(), "expected 2.5")
+	}
+	e.Reset()
+	buf.Push(3)
+	if e.Value() != 3 {
+		t.Error("Value() after Reset() =", e.Value())
+	}
+}
+
+func TestEWMAHalfLife(t *testing.T) {
+	e := NewEWMASamples(10)
+	e.Update(100)
+	for i := 0; i < 10; i++ {
+		e.Update(0)
+	}
+	if !near(e.Value(), 50) {
+		t.Error("after one half-life Value() =", e.Value(), "expected 50")
+	}
+
+	start := time.Unix(0, 0)
+	e = NewEWMATime(time.Minute)
+	buf := circularbuffer.New(8, Sample{}, e.SampleOption())
+	buf.Push(Sample{start, 100})
+	buf.Push(Sample{start.Add(2 * time.Minute), 0})
+	if !near(e.Value(), 25) {
+		t.Error("after two half-lives Value() =", e.Value(), "expected 25")
+	}
+}
+
+func TestEWMATimeOption(t *testing.T) {
+	// A half-life far shorter than the time between pushes makes each
+	// value replace the average.
+	e := NewEWMATime(time.Nanosecond)
+	buf := circularbuffer.New(4, 0.0, e.Option())
+	buf.Push(1)
+	time.Sleep(time.Millisecond)
+	buf.Push(5)
+	if !near(e.Value(), 5) {
+		t.Error("Value() =", e.Value(), "expected 5")
+	}
+}
+
+func TestEWMAOutOfOrder(t *testing.T) {
+	start := time.Unix(0, 0)
+	e := NewEWMATime(time.Minute)
+	e.UpdateAt(start.Add(time.Minute), 100)
+	e.UpdateAt(start, 0)
+	if e.Value() != 100 {
+		t.Error("older sample changed Value() to", e.Value())
+	}
+	e.UpdateAt(start.Add(2*time.Minute), 0)
+	if !near(e.Value(), 50) {
+		t.Error("Value() =", e.Value(), "expected 50")
+	}
+}
+
+func TestRate(t *testing.T) {
+	r := NewRate()
+	buf := circularbuffer.New(5, time.Time{}, r.Option())
+	if r.PerSecond() != 0 {
+		t.Error("PerSecond() without events =", r.PerSecond())
+	}
+	start := time.Unix(100, 0)
+	// 4 events 500ms apart: 3 intervals in 1.5s.
+	for i := 0; i < 4; i++ {
+		buf.Push(start.Add(time.Duration(i) * 500 * time.Millisecond))
+	}
+	if r.Count() != 4 || !near(r.PerSecond(), 2) {
+		t.Error("Count() =", r.Count(), "PerSecond() =", r.PerSecond(), "expected 2")
+	}
+	// Slowing down: the window of 5 now spans 2s..6s.
+	for i := 2; i <= 6; i++ {
+		buf.Push(start.Add(time.Duration(i) * time.Second))
+	}
+	if r.Count() != 5 || !near(r.PerSecond(), 1) {
+		t.Error("Count() =", r.Count(), "PerSecond() =", r.PerSecond(), "expected 1")
+	}
+}
+
+func TestDerivative(t *testing.T) {
+	d := NewDerivative()
+	buf := circularbuffer.New(3, Sample{}, d.Option())
+	start := time.Unix(0, 0)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	buf.Push(Sample{at(0), 10})
+	if _, ok := d.Last(); ok {
+		t.Error("Last() with one sample returned ok")
+	}
+	buf.Push(Sample{at(2), 14})
+	buf.Push(Sample{at(3), 20})
+	buf.Push(Sample{at(5), 10})
+	if v, _ := d.Last(); !near(v, -5) {
+		t.Error("Last() =", v, "expected -5")
+	}
+	// Oldest held is 14 at 2s.
+	if v, _ := d.Average(); !near(v, -4.0/3) {
+		t.Error("Average() =", v, "expected -4/3")
+	}
+}
+
+func TestSeveralHooks(t *testing.T) {
+	e := NewEWMA(1)
+	d := NewDerivative()
+	buf := circularbuffer.New(4, Sample{}, e.SampleOption(), d.Option())
+	buf.Push(Sample{time.Unix(0, 0), 1})
+	buf.Push(Sample{time.Unix(1, 0), 3})
+	if v, _ := d.Last(); e.Value() != 3 || v != 2 {
+		t.Error("hooks disagree:", e.Value(), v)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+// Package worksteal provides a Chase-Lev work-stealing deque and a worker
+// pool scheduler built on it. The owner of a deque pushes and pops at the
+// bottom without locks, like a stack, while other goroutines steal the
+// oldest elements from the top with a compare-and-swap. When the circular
+// array fills it is replaced by one twice the size, as deque.Deque does.
+package worksteal
+
+import "sync/atomic"
+
+// cacheLine pads top away from bottom, so that thieves advancing top do
+// not keep invalidating the owner's cache line holding bottom.
+const cacheLine = 64
+
+// minSize is the smallest circular array, a power of 2.
+const minSize = 16
+
+// ring is a circular array indexed by the unbounded top and bottom
+// positions of a Deque. Slots hold pointers so that thieves can read
+// them while the owner writes others.
+type ring[T any] struct {
+	mask  int64
+	slots []atomic.Pointer[T]
+}
+
+func newRing[T any](size int) *ring[T] {
+	return &ring[T]{mask: int64(size - 1), slots: make([]atomic.Pointer[T], size)}
+}
+
+func (r *ring[T]) get(i int64) *T    { return r.slots[i&r.mask].Load() }
+func (r *ring[T]) put(i int64, v *T) { r.slots[i&r.mask].Store(v) }
+
+// grow returns a ring twice the size holding positions top to bottom.
+// The old ring is left untouched for thieves still reading from it.
+func (r *ring[T]) grow(top, bottom int64) *ring[T] {
+	n := newRing[T](len(r.slots) << 1)
+	for i := top; i < bottom; i++ {
+		n.put(i, r.get(i))
+	}
+	return n
+}
+
+// Deque is a work-stealing deque. Push and Pop may only be called by the
+// goroutine owning the deque; Steal and Len may be called by any.
+type Deque[T any] struct {
+	_ [cacheLine]byte
+	// top is the next position to steal.
+	top atomic.Int64
+	_   [cacheLine - 8]byte
+	// bottom is the next position to push.
+	bottom atomic.Int64
+	_      [cacheLine - 8]byte
+	array  atomic.Pointer[ring[T]]
+}
+
+// New returns an empty deque whose array starts with room for at least
+// capacity elements, rounded up to a power of 2.
+func New[T any](capacity int) *Deque[T] {
+	size := minSize
+	for size < capacity {
+		size <<= 1
+	}
+	d := &Deque[T]{}
+	d.array.Store(newRing[T](size))
+	return d
+}
+
+// Len returns the number of elements in the deque. With thieves running
+// it is only a snapshot.
+func (d *Deque[T]) Len() int {
+	n := d.bottom.Load() - d.top.Load()
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// Push adds v at the bottom of the deque, growing the array if it is
+// full. Only the owner may call Push.
+func (d *Deque[T]) Push(v T) {
+	b := d.bottom.Load()
+	t := d.top.Load()
+	a := d.array.Load()
+	if b-t > a.mask {
+		a = a.grow(t, b)
+		d.array.Store(a)
+	}
+	a.put(b, &v)
+	d.bottom.Store(b + 1)
+}
+
+// Pop removes and returns the element at the bottom of the deque, the one
+// pushed last. Only the owner may call Pop.
+func (d *Deque[T]) Pop() (T, bool) {
+	var zero T
+	b := d.bottom.Load() - 1
+	a := d.array.Load()
+	d.bottom.Store(b)
+	t := d.top.Load()
+	if t > b {
+		// Empty: thieves took everything.
+		d.bottom.Store(b + 1)
+		return zero, false
+	}
+	p := a.get(b)
+	if t == b {
+		// Last element: race the thieves for it.
+		won := d.top.CompareAndSwap(t, t+1)
+		d.bottom.Store(b + 1)
+		if !won {
+			return zero, false
+		}
+		return *p, true
+	}
+	a.put(b, nil)
+	return *p, true
+}
+
+// Steal removes and returns the element at the top of the deque, the
+// oldest one. It retries while it loses races with other thieves, and
+// returns false once the deque is empty.
+func (d *Deque[T]) Steal() (T, bool) {
+	for {
+		t := d.top.Load()
+		b := d.bottom.Load()
+		if t >= b {
+			var zero T
+			return zero, false
+		}
+		p := d.array.Load().get(t)
+		if d.top.CompareAndSwap(t, t+1) {
+			return *p, true
+		}
+	}
+}
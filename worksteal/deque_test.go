@@ -0,0 +1,98 @@
+package worksteal
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPushPopSteal(t *testing.T) {
+	d := New[int](0)
+	if _, ok := d.Pop(); ok {
+		t.Fatal("Pop on empty deque succeeded")
+	}
+	if _, ok := d.Steal(); ok {
+		t.Fatal("Steal on empty deque succeeded")
+	}
+	// Push past the initial 16 slots several times over.
+	for i := 0; i < 100; i++ {
+		d.Push(i)
+	}
+	if d.Len() != 100 {
+		t.Fatal("Len() =", d.Len())
+	}
+	for i := 0; i < 10; i++ {
+		if v, ok := d.Steal(); !ok || v != i {
+			t.Fatalf("Steal() = %d, %v, expected %d", v, ok, i)
+		}
+	}
+	for i := 99; i >= 10; i-- {
+		if v, ok := d.Pop(); !ok || v != i {
+			t.Fatalf("Pop() = %d, %v, expected %d", v, ok, i)
+		}
+	}
+	if _, ok := d.Pop(); ok || d.Len() != 0 {
+		t.Error("deque not empty, Len() =", d.Len())
+	}
+	d.Push(7)
+	if v, ok := d.Steal(); !ok || v != 7 {
+		t.Error("Steal() after reuse =", v, ok)
+	}
+}
+
+func TestNewCapacity(t *testing.T) {
+	for capacity, want := range map[int]int{0: 16, 16: 16, 17: 32, 100: 128} {
+		if got := len(New[int](capacity).array.Load().slots); got != want {
+			t.Errorf("New(%d) array size %d, expected %d", capacity, got, want)
+		}
+	}
+}
+
+// TestStealStress checks that with the owner pushing and popping while
+// thieves steal, every element is taken exactly once.
+func TestStealStress(t *testing.T) {
+	const n, thieves = 50000, 4
+	d := New[int](0)
+	seen := make([]atomic.Int32, n)
+	var done atomic.Bool
+	var wg sync.WaitGroup
+	for i := 0; i < thieves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if v, ok := d.Steal(); ok {
+					seen[v].Add(1)
+					continue
+				}
+				if done.Load() {
+					return
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		d.Push(i)
+		if i%3 == 0 {
+			if v, ok := d.Pop(); ok {
+				seen[v].Add(1)
+			}
+		}
+	}
+	for {
+		v, ok := d.Pop()
+		if !ok {
+			break
+		}
+		seen[v].Add(1)
+	}
+	done.Store(true)
+	wg.Wait()
+	for i := range seen {
+		if c := seen[i].Load(); c != 1 {
+			t.Fatalf("element %d taken %d times", i, c)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 Gustavo Maurizio
+// Permission is hereby granted, free of charge, to any person obtaining a
+// copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+// OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+// THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+//
+
+package worksteal
+
+import (
+	"errors"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gus-maurizio/structures/deque/v2"
+	"github.com/gus-maurizio/structures/internal/broadcast"
+)
+
+// ErrClosed is returned by Submit once the pool is closed.
+var ErrClosed = errors.New("worksteal: pool closed")
+
+// Task is a unit of work run by a worker of a Pool. The worker is passed
+// in so that the task can spawn subtasks onto its deque.
+type Task func(w *Worker)
+
+// Pool runs tasks on a fixed set of workers, each with its own Deque.
+// Tasks spawned by a running task go to the bottom of its worker's deque
+// and are run last in, first out; an idle worker steals the oldest tasks
+// of the others. Tasks submitted from outside the pool go through a
+// shared queue.
+type Pool struct {
+	workers []*Worker
+	pending atomic.Int64 // tasks submitted or spawned and not yet finished
+
+	mu     sync.Mutex
+	inject deque.Deque[Task]
+	closed bool
+	// idle is broadcast when pending drops to zero.
+	idle broadcast.Signal
+
+	// wake holds up to one token per worker so that a task added while a
+	// worker is going to sleep is not missed.
+	wake    chan struct{}
+	quit    chan struct{}
+	running sync.WaitGroup
+}
+
+// Worker is a goroutine of a Pool.
+type Worker struct {
+	id    int
+	pool  *Pool
+	local *Deque[Task]
+}
+
+// NewPool starts a pool of n workers. An n below 1 is taken as
+// runtime.GOMAXPROCS(0).
+func NewPool(n int) *Pool {
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	p := &Pool{
+		workers: make([]*Worker, n),
+		wake:    make(chan struct{}, n),
+		quit:    make(chan struct{}),
+	}
+	for i := range p.workers {
+		p.workers[i] = &Worker{id: i, pool: p, local: New[Task](0)}
+	}
+	p.running.Add(n)
+	for _, w := range p.workers {
+		go w.run()
+	}
+	return p
+}
+
+// Size returns the number of workers.
+func (p *Pool) Size() int { return len(p.workers) }
+
+// Submit queues t to run on some worker. It returns ErrClosed once the
+// pool is closed.
+func (p *Pool) Submit(t Task) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.pending.Add(1)
+	p.inject.PushLast(t)
+	p.mu.Unlock()
+	p.notify()
+	return nil
+}
+
+// Wait returns once every task submitted or spawned so far, and every
+// task they spawn, has finished.
+func (p *Pool) Wait() {
+	p.mu.Lock()
+	// A task submitted after pending dropped to zero, but before done
+	// broadcast it, is still running when idle is closed: check again.
+	for p.pending.Load() != 0 {
+		idle := p.idle.Wait()
+		p.mu.Unlock()
+		<-idle
+		p.mu.Lock()
+	}
+	p.mu.Unlock()
+}
+
+// Close stops the pool from accepting tasks, waits for the queued ones to
+// finish and stops the workers. Closing a closed pool has no effect.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	p.mu.Unlock()
+	p.Wait()
+	close(p.quit)
+	p.running.Wait()
+}
+
+// notify wakes a sleeping worker, if there is room for a token.
+func (p *Pool) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// done records that a task finished.
+func (p *Pool) done() {
+	if p.pending.Add(-1) == 0 {
+		p.mu.Lock()
+		if p.pending.Load() == 0 {
+			p.idle.Broadcast()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// ID returns the index of the worker in its pool, from 0 to Size()-1.
+func (w *Worker) ID() int { return w.id }
+
+// Spawn queues t on the worker's own deque. It may only be called by a
+// task running on w.
+func (w *Worker) Spawn(t Task) {
+	w.pool.pending.Add(1)
+	w.local.Push(t)
+	w.pool.notify()
+}
+
+func (w *Worker) run() {
+	defer w.pool.running.Done()
+	for {
+		if t, ok := w.find(); ok {
+			t(w)
+			w.pool.done()
+			continue
+		}
+		select {
+		case <-w.pool.wake:
+		case <-w.pool.quit:
+			return
+		}
+	}
+}
+
+// find returns the next task for w: its own newest task, else the oldest
+// submitted task, else the oldest task of another worker.
+func (w *Worker) find() (Task, bool) {
+	if t, ok := w.local.Pop(); ok {
+		return t, true
+	}
+	p := w.pool
+	p.mu.Lock()
+	t, ok := p.inject.PopFirst()
+	p.mu.Unlock()
+	if ok {
+		return t, true
+	}
+	n := len(p.workers)
+	start := rand.IntN(n)
+	for i := 0; i < n; i++ {
+		if v := p.workers[(start+i)%n]; v != w {
+			if t, ok := v.local.Steal(); ok {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,90 @@
+package worksteal
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// tree spawns a binary tree of tasks of the given depth, counting them.
+func tree(count *atomic.Int64, depth int) Task {
+	return func(w *Worker) {
+		count.Add(1)
+		if depth > 0 {
+			w.Spawn(tree(count, depth-1))
+			w.Spawn(tree(count, depth-1))
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	p := NewPool(4)
+	defer p.Close()
+	if p.Size() != 4 {
+		t.Fatal("Size() =", p.Size())
+	}
+	var count atomic.Int64
+	for i := 0; i < 8; i++ {
+		if err := p.Submit(tree(&count, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Wait()
+	if want := int64(8 * (1<<11 - 1)); count.Load() != want {
+		t.Errorf("ran %d tasks, expected %d", count.Load(), want)
+	}
+
+	// The pool is reusable after Wait.
+	count.Store(0)
+	p.Submit(tree(&count, 3))
+	p.Wait()
+	if count.Load() != 15 {
+		t.Errorf("ran %d tasks, expected 15", count.Load())
+	}
+}
+
+func TestPoolWorkerIDs(t *testing.T) {
+	p := NewPool(3)
+	defer p.Close()
+	var bad atomic.Bool
+	for i := 0; i < 100; i++ {
+		p.Submit(func(w *Worker) {
+			if w.ID() < 0 || w.ID() >= 3 || p.workers[w.ID()] != w {
+				bad.Store(true)
+			}
+		})
+	}
+	p.Wait()
+	if bad.Load() {
+		t.Error("task ran with a worker of the wrong ID")
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	p := NewPool(0)
+	var count atomic.Int64
+	p.Submit(tree(&count, 5))
+	p.Close()
+	if count.Load() != 63 {
+		t.Errorf("Close returned after %d of 63 tasks", count.Load())
+	}
+	if err := p.Submit(tree(&count, 0)); err != ErrClosed {
+		t.Error("Submit after Close returned", err)
+	}
+	p.Close()
+	p.Wait()
+}
+
+func TestPoolWaitAfterSubmit(t *testing.T) {
+	p := NewPool(2)
+	defer p.Close()
+	// Each Wait races the done of the previous task, which may still be
+	// about to report the pool idle.
+	var count atomic.Int64
+	for i := 1; i <= 20000; i++ {
+		p.Submit(func(*Worker) { count.Add(1) })
+		p.Wait()
+		if n := count.Load(); n != int64(i) {
+			t.Fatalf("Wait returned after %d of %d tasks", n, i)
+		}
+	}
+}